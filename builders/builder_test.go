@@ -114,3 +114,32 @@ func TestMarkFriday(t *testing.T) {
 	ws.Wait()
 	mark.Close()
 }
+
+func TestMarkFridayCorrelation(t *testing.T) {
+	ws := new(sync.WaitGroup)
+	ws.Add(1)
+
+	mark := MarkFriday(MarkConfig{
+		SaveDir: "../fixtures/templates",
+		Ext:     ".mdr",
+	})
+
+	mark.React((func(root flux.Reactor, err error, data interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Error  occured %+s", err)
+		}
+
+		if id := fs.IDOf(data); id != "change-1" {
+			flux.FatalFailed(t, "Expected the correlation ID on the FileWrite: %+s", data)
+		}
+
+		flux.LogPassed(t, "Correlation ID carried to the written file: %s", fs.IDOf(data))
+	}), true)
+
+	mark.Send(&fs.Envelope{ID: "change-1", Data: "../fixtures/markdown/base.md"})
+
+	ws.Wait()
+	mark.Close()
+}
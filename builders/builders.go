@@ -2,16 +2,21 @@ package builders
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influx6/assets"
 	"github.com/influx6/flux"
 	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+	"github.com/influx6/reactors/metrics"
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/russross/blackfriday"
 )
@@ -37,64 +42,56 @@ func BundleAssets(config *assets.BindFSConfig) (flux.Reactor, error) {
 // GoInstaller calls `go install` from the path it receives from its data pipes
 func GoInstaller() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
-		if path, ok := data.(string); ok {
+		if path, ok := fs.Unwrap(data).(string); ok {
 			if err := GoDeps(path); err != nil {
 				root.ReplyError(err)
 				return
 			}
-			root.Reply(true)
+			root.Reply(fs.Wrap(fs.IDOf(data), true))
 		}
 	}))
 }
 
 // GoInstallerWith calls `go install` everysingle time to the provided path once a signal is received
 func GoInstallerWith(path string) flux.Reactor {
-	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, _ interface{}) {
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if err := GoDeps(path); err != nil {
 			root.ReplyError(err)
 			return
 		}
-		root.Reply(true)
+		root.Reply(fs.Wrap(fs.IDOf(data), true))
 	}))
 }
 
 // GoRunner calls `go run` with the command it receives from its data pipes
 func GoRunner() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
-		if cmd, ok := data.(string); ok {
-			root.Reply(GoRun(cmd))
+		if cmd, ok := fs.Unwrap(data).(string); ok {
+			root.Reply(fs.Wrap(fs.IDOf(data), GoRun(cmd)))
 		}
 	}))
 }
 
 // GoRunnerWith calls `go run` everysingle time to the provided path once a signal is received
 func GoRunnerWith(cmd string) flux.Reactor {
-	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, _ interface{}) {
-		root.Reply(GoRun(cmd))
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		root.Reply(fs.Wrap(fs.IDOf(data), GoRun(cmd)))
 	}))
 }
 
 // BuildConfig defines a configuration to be passed into a GoBuild/GoBuildWith Task
 type BuildConfig struct {
-	Path string
-	Name string
-	Args []string
-}
-
-func validateBuildConfig(b BuildConfig) {
-	if b.Name == "" {
-		panic("buildConfig.Name can not be empty,supply a name for the build")
-	}
-
-	if b.Path == "" {
-		panic("buildConfig.Path can not be empty,supply a path to store the build")
-	}
+	Path    string
+	Name    string
+	Args    []string
+	Logger  logs.Logger      // Optional: Logger receives the build status messages, defaults to the global logs logger
+	Metrics metrics.Recorder // Optional: Metrics records the build counts, durations and spans, defaults to the global metrics recorder
 }
 
 // GoBuilder calls `go run` with the command it receives from its data pipes, using the GoBuild function
 func GoBuilder() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
-		if cmd, ok := data.(BuildConfig); ok {
+		if cmd, ok := fs.Unwrap(data).(BuildConfig); ok {
 			if err := Gobuild(cmd.Path, cmd.Name, cmd.Args); err != nil {
 				root.ReplyError(err)
 			}
@@ -102,49 +99,68 @@ func GoBuilder() flux.Reactor {
 	}))
 }
 
-// GoBuilderWith calls `go run` everysingle time to the provided path once a signal is received using the GoBuild function
+// GoBuilderWith calls `go run` everysingle time to the provided path once a signal is received using the GoBuild function, it panics if the config is invalid
 func GoBuilderWith(cmd BuildConfig) flux.Reactor {
-	validateBuildConfig(cmd)
-	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, _ interface{}) {
-		if err := Gobuild(cmd.Path, cmd.Name, cmd.Args); err != nil {
+	return mustReactor(NewGoBuilderWith(cmd))
+}
+
+// NewGoBuilderWith returns a GoBuilderWith task or a *ValidationError if the config is invalid
+func NewGoBuilderWith(cmd BuildConfig) (flux.Reactor, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		if err := gobuild(cmd.Path, cmd.Name, cmd.Args, fs.IDOf(data), cmd.Logger, cmd.Metrics); err != nil {
 			root.ReplyError(err)
 			return
 		}
-		root.Reply(true)
-	}))
+		root.Reply(fs.Wrap(fs.IDOf(data), true))
+	})), nil
 }
 
 // GoArgsBuilder calls `go run` with the command it receives from its data pipes usingthe GobuildArgs function
 func GoArgsBuilder() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
-		if cmd, ok := data.([]string); ok {
+		if cmd, ok := fs.Unwrap(data).([]string); ok {
 			if err := GobuildArgs(cmd); err != nil {
 				root.ReplyError(err)
 				return
 			}
-			root.Reply(true)
+			root.Reply(fs.Wrap(fs.IDOf(data), true))
 		}
 	}))
 }
 
 // GoArgsBuilderWith calls `go run` everysingle time to the provided path once a signal is received using the GobuildArgs function
 func GoArgsBuilderWith(cmd []string) flux.Reactor {
-	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, _ interface{}) {
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if err := GobuildArgs(cmd); err != nil {
 			root.ReplyError(err)
 			return
 		}
-		root.Reply(true)
+		root.Reply(fs.Wrap(fs.IDOf(data), true))
 	}))
 }
 
-// CommandLauncher returns a new Task generator that builds a command executor that executes a series of command every time it receives a signal, it sends out a signal onces its done running all commands
+// CommandLauncher returns a new Task generator that builds a command executor that executes a series of command every time it receives a signal, it sends out a signal onces its done running all commands, it panics if the list is empty
 func CommandLauncher(cmd []string) flux.Reactor {
+	return mustReactor(NewCommandLauncher(cmd))
+}
+
+// NewCommandLauncher returns a CommandLauncher task or ErrNoCommands if the list is empty
+func NewCommandLauncher(cmd []string) (flux.Reactor, error) {
+	if len(cmd) == 0 {
+		return nil, ErrNoCommands
+	}
+
 	var channel chan bool
-	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, _ interface{}) {
+	var last correlation
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if channel == nil {
-			channel = RunCMD(cmd, func() {
-				root.Reply(true)
+			channel, _ = RunCommands(cmd, func() {
+				root.Reply(fs.Wrap(last.get(), true))
 			})
 		}
 
@@ -153,23 +169,31 @@ func CommandLauncher(cmd []string) flux.Reactor {
 			close(channel)
 			return
 		case <-time.After(0):
+			last.set(fs.IDOf(data))
 			channel <- true
 		}
 
-	}))
+	})), nil
 }
 
 // BinaryLauncher returns a new Task generator that builds a binary runner from the given properties, which causing a relaunch of a binary file everytime it recieves a signal,  it sends out a signal onces its done running all commands
 func BinaryLauncher(bin string, args []string) flux.Reactor {
+	return binaryLauncher(bin, args, nil, nil)
+}
+
+// binaryLauncher returns a BinaryLauncher logging into the given logger and recording into the given recorder or the
+// global ones if nil
+func binaryLauncher(bin string, args []string, logger logs.Logger, recorder metrics.Recorder) flux.Reactor {
 	var channel chan bool
+	var last correlation
 
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if channel == nil {
-			channel = RunBin(bin, args, func() {
-				root.Reply(true)
+			channel = runBin(bin, args, func() {
+				root.Reply(fs.Wrap(last.get(), true))
 			}, func() {
 				go root.Close()
-			})
+			}, logger, recorder, last.get)
 		}
 
 		select {
@@ -178,7 +202,8 @@ func BinaryLauncher(bin string, args []string) flux.Reactor {
 			return
 		case <-time.After(0):
 			//force check of boolean values to ensure we can use correct signal
-			if cmd, ok := data.(bool); ok {
+			if cmd, ok := fs.Unwrap(data).(bool); ok {
+				last.set(fs.IDOf(data))
 				channel <- cmd
 				return
 			}
@@ -194,24 +219,23 @@ func BinaryLauncher(bin string, args []string) flux.Reactor {
 type BinaryBuildConfig struct {
 	Path      string
 	Name      string
-	BuildArgs []string //arguments to be used in building
-	RunArgs   []string //arguments to be used in running
-}
-
-func validateBinaryBuildConfig(b BinaryBuildConfig) {
-	if b.Name == "" {
-		panic("buildConfig.Name can not be empty,supply a name for the build")
-	}
-
-	if b.Path == "" {
-		panic("buildConfig.Path can not be empty,supply a path to store the build")
-	}
+	BuildArgs []string         //arguments to be used in building
+	RunArgs   []string         //arguments to be used in running
+	Logger    logs.Logger      // Optional: Logger receives the build and process messages, defaults to the global logs logger
+	Metrics   metrics.Recorder // Optional: Metrics records the builds and process restarts, defaults to the global metrics recorder
 }
 
 // BinaryBuildLauncher combines the builder and binary runner to provide a simple and order-based process,
 // the BinaryLauncher is only created to handling a binary lunching making it abit of a roundabout to time its response to wait until another process finishes, but BinaryBuildLuncher cleans out the necessity and provides a reactor that embedds the necessary call routines while still response the: Build->Run or StopRunning->Build->Run process in development
 func BinaryBuildLauncher(cmd BinaryBuildConfig) flux.Reactor {
-	validateBinaryBuildConfig(cmd)
+	return mustReactor(NewBinaryBuildLauncher(cmd))
+}
+
+// NewBinaryBuildLauncher returns a BinaryBuildLauncher task or a *ValidationError if the config is invalid
+func NewBinaryBuildLauncher(cmd BinaryBuildConfig) (flux.Reactor, error) {
+	if err := cmd.Validate(); err != nil {
+		return nil, err
+	}
 
 	// first generate the output file name from the config
 	var basename = cmd.Name
@@ -226,10 +250,14 @@ func BinaryBuildLauncher(cmd BinaryBuildConfig) flux.Reactor {
 	buildStack := flux.ReactorStack()
 
 	//package builder
-	builder := GoBuilderWith(BuildConfig{Path: cmd.Path, Name: cmd.Name, Args: cmd.BuildArgs})
+	builder, err := NewGoBuilderWith(BuildConfig{Path: cmd.Path, Name: cmd.Name, Args: cmd.BuildArgs, Logger: cmd.Logger, Metrics: cmd.Metrics})
+
+	if err != nil {
+		return nil, err
+	}
 
 	//package runner
-	runner := BinaryLauncher(binfile, cmd.RunArgs)
+	runner := binaryLauncher(binfile, cmd.RunArgs, cmd.Logger, cmd.Metrics)
 
 	//when buildStack receives a signal, we will send a bool(false) signal to runner to kill the current process
 	buildStack.React(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
@@ -244,17 +272,18 @@ func BinaryBuildLauncher(cmd BinaryBuildConfig) flux.Reactor {
 	buildStack.Bind(builder, true)
 	buildStack.Bind(runner, true)
 
-	return buildStack
+	return buildStack, nil
 }
 
 // GoFileLauncher returns a new Task generator that builds a binary runner from the given properties, which causing a relaunch of a binary file everytime it recieves a signal,  it sends out a signal onces its done running all commands
 func GoFileLauncher(goFile string, args []string) flux.Reactor {
 	var channel chan bool
+	var last correlation
 
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if channel == nil {
 			channel = RunGo(goFile, args, func() {
-				root.Reply(true)
+				root.Reply(fs.Wrap(last.get(), true))
 			}, func() {
 				go root.Close()
 			})
@@ -265,6 +294,7 @@ func GoFileLauncher(goFile string, args []string) flux.Reactor {
 			close(channel)
 			return
 		case <-time.After(0):
+			last.set(fs.IDOf(data))
 			channel <- true
 		}
 
@@ -279,22 +309,65 @@ type JSBuildConfig struct {
 	PackageDir string   // Optional: PackageDir is an optional directory to be imported into build process
 	Tags       []string //Optional: Tags are optional build tags for build process
 	Verbose    bool     // Optional: verbose value for gopherjs builder
+
+	Profile   BuildProfile  // Optional: DebugProfile or ReleaseProfile presets for Minify and SourceMap
+	Minify    MinifyMode    // Optional: MinifyOn or MinifyOff overrides the profile minify setting
+	SourceMap SourceMapMode // Optional: SourceMapFile, SourceMapInline or SourceMapNone overrides the profile source map mode
+	GOOS      string        // Optional: GOOS value added as a build tag eg. linux
+	GOARCH    string        // Optional: GOARCH value added as a build tag eg. amd64
+
+	Fingerprint  bool   // Optional: if true, adds a content hash to the output names eg. app.3f9c2a10.js and maintains an AssetManifest
+	ManifestName string // Optional: ManifestName is the AssetManifest file name within the Folder, defaults to assets.json
+
+	Budget     SizeBudget // Optional: size budgets checked against the SizeReport of every build
+	ReportName string     // Optional: if set, the SizeReport is written within the Folder as html for .html names else as json, even when the build fails its Budget
+
+	Logger  logs.Logger      // Optional: Logger receives the build status messages, defaults to the global logs logger
+	Metrics metrics.Recorder // Optional: Metrics records the build counts, durations and spans, defaults to the global metrics recorder
 }
 
-// JSBuildLauncher returns a Task generator that builds a new jsbuild task giving the specific configuration and on every reception of signals rebuilds and sends off a FileWrite for each file i.e the js and js.map file
+// Options returns the JSOptions described by the config
+func (j JSBuildConfig) Options() JSOptions {
+	return JSOptions{
+		Profile:   j.Profile,
+		Minify:    j.Minify,
+		SourceMap: j.SourceMap,
+		GOOS:      j.GOOS,
+		GOARCH:    j.GOARCH,
+		Tags:      j.Tags,
+		Verbose:   j.Verbose,
+	}
+}
+
+// JSBuildLauncher returns a Task generator that builds a new jsbuild task giving the specific configuration and on every reception of signals rebuilds and sends off a FileWrite for each file i.e the js and js.map file, the js.map is only sent when the config produces a separate source map file and when
+// fingerprinting is enabled the files get hashed names followed by a FileWrite of the updated AssetManifest and a
+// *fs.RemoveFile for each hashed file of the previous manifest replaced by the build. Outputs
+// going over the config Budget reply a *BudgetWarning or a *BudgetError if the budget is set to fail, while failed
+// compilations reply a *DiagnosticError
 func JSBuildLauncher(config JSBuildConfig) flux.Reactor {
-	if config.Package == "" {
-		panic("JSBuildConfig.Package can not be empty")
+	return mustReactor(NewJSBuildLauncher(config))
+}
+
+// NewJSBuildLauncher returns a JSBuildLauncher task or a *ValidationError if the config is invalid
+func NewJSBuildLauncher(config JSBuildConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
 	}
 
 	if config.FileName == "" {
 		config.FileName = "jsapp.build"
 	}
 
+	if config.ManifestName == "" {
+		config.ManifestName = "assets.json"
+	}
+
 	// var session *JSSession
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		id := fs.IDOf(data)
+
 		// if session == nil {
-		session := NewJSSession(config.Tags, config.Verbose, false)
+		session := NewJSSessionWith(config.Options())
 		// }
 
 		// session.Session.
@@ -303,6 +376,9 @@ func JSBuildLauncher(config JSBuildConfig) flux.Reactor {
 		var js, jsmap *bytes.Buffer
 		var err error
 
+		start := time.Now()
+		done := metrics.StartBuild(config.Metrics, id, "JSBuildLauncher", metrics.L("path", config.Package))
+
 		if config.PackageDir != "" {
 			js, jsmap, err = session.BuildDir(config.PackageDir, config.Package, config.FileName)
 		} else {
@@ -310,44 +386,309 @@ func JSBuildLauncher(config JSBuildConfig) flux.Reactor {
 		}
 
 		if err != nil {
+			logs.Error(config.Logger, "js build failed", logs.Task("JSBuildLauncher"), logs.Path(config.Package), logs.Duration(time.Since(start)), logs.Err(err))
+			done(err)
 			root.ReplyError(err)
 			return
 		}
 
-		jsfile := fmt.Sprintf("%s.js", config.FileName)
-		jsmapfile := fmt.Sprintf("%s.js.map", config.FileName)
+		logs.Info(config.Logger, "js build finished", logs.Task("JSBuildLauncher"), logs.Path(config.Package), logs.Duration(time.Since(start)), logs.F("size", js.Len()))
+		done(nil)
 
-		root.Reply(&fs.FileWrite{Data: js.Bytes(), Path: filepath.Join(config.Folder, jsfile)})
-		root.Reply(&fs.FileWrite{Data: jsmap.Bytes(), Path: filepath.Join(config.Folder, jsmapfile)})
-	}))
+		report := NewSizeReport(fmt.Sprintf("%s.js", config.FileName), js.Bytes())
+		violations := config.Budget.Check(report)
+
+		for _, violation := range violations {
+			logs.Warn(config.Logger, "js size budget exceeded", logs.Task("JSBuildLauncher"), logs.Path(config.Package), logs.F("name", violation.Name), logs.F("size", violation.Size), logs.F("budget", violation.Budget))
+		}
+
+		//the report is written before failing so the build going over the budget can be inspected
+		if config.ReportName != "" {
+			rdata, err := report.Render(config.ReportName)
+
+			if err != nil {
+				root.ReplyError(err)
+				return
+			}
+
+			root.Reply(&fs.FileWrite{Data: rdata, Path: filepath.Join(config.Folder, config.ReportName), ID: id})
+		}
+
+		if len(violations) > 0 && config.Budget.Fail {
+			root.ReplyError(&BudgetError{Violations: violations})
+			return
+		}
+
+		if len(violations) > 0 {
+			root.Reply(&BudgetWarning{Violations: violations, ID: id})
+		}
+
+		base, jsdata := config.FileName, js.Bytes()
+
+		if config.Fingerprint {
+			base, jsdata = fingerprintJS(config.FileName, jsdata)
+		}
+
+		jsfile := fmt.Sprintf("%s.js", base)
+		jsmapfile := fmt.Sprintf("%s.js.map", base)
+
+		root.Reply(&fs.FileWrite{Data: jsdata, Path: filepath.Join(config.Folder, jsfile), ID: id})
+
+		//only a SourceMapFile session produces a separate map file
+		if session.SourceMap == SourceMapFile {
+			root.Reply(&fs.FileWrite{Data: jsmap.Bytes(), Path: filepath.Join(config.Folder, jsmapfile), ID: id})
+		}
+
+		if !config.Fingerprint {
+			return
+		}
+
+		//update the manifest already on disk so entries written by other builds are kept
+		manifestFile := filepath.Join(config.Folder, config.ManifestName)
+		manifest, err := ReadAssetManifest(manifestFile)
+
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+
+		var stale []string
+
+		if old := manifest.Replace(fmt.Sprintf("%s.js", config.FileName), jsfile, jsdata); old != "" {
+			stale = append(stale, old)
+		}
+
+		if session.SourceMap == SourceMapFile {
+			if old := manifest.Replace(fmt.Sprintf("%s.js.map", config.FileName), jsmapfile, jsmap.Bytes()); old != "" {
+				stale = append(stale, old)
+			}
+		}
+
+		mdata, err := json.MarshalIndent(manifest, "", "  ")
+
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+
+		root.Reply(&fs.FileWrite{Data: mdata, Path: manifestFile, ID: id})
+
+		for _, file := range stale {
+			root.Reply(&fs.RemoveFile{Path: filepath.Join(config.Folder, file), ID: id})
+		}
+	})), nil
 }
 
-// JSLauncher returns a reactor that on receiving a signal builds the gopherjs package as giving in the config and writes it out using a FileWriter
+// JSLauncher returns a reactor that on receiving a signal builds the gopherjs package as giving in the config and writes it out using a FileWriter,
+// removing the stale fingerprinted files with a FileRemover
 func JSLauncher(config JSBuildConfig) flux.Reactor {
-	stack := flux.ReactStack(JSBuildLauncher(config))
-	stack.Bind(fs.FileWriter(nil), true)
+	return mustReactor(NewJSLauncher(config))
+}
+
+// NewJSLauncher returns a JSLauncher task or a *ValidationError if the config is invalid
+func NewJSLauncher(config JSBuildConfig) (flux.Reactor, error) {
+	builder, err := NewJSBuildLauncher(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return writeOutputs(builder, config.Metrics), nil
+}
+
+// writeOutputs returns a stack writing the *fs.FileWrite replies of the builder with a FileWriter recording into the
+// recorder and removing the *fs.RemoveFile replies with a FileRemover, replying the writes and removals
+func writeOutputs(builder flux.Reactor, recorder metrics.Recorder) flux.Reactor {
+	//the tail replies the writes and removals of the builder
+	tail := flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+		root.Reply(data)
+	})
+
+	remover := fs.FileRemover()
+
+	remover.React(func(_ flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			tail.SendError(err)
+			return
+		}
+		tail.Send(data)
+	}, true)
+
+	stack := flux.ReactStack(builder)
+
+	stack.Bind(flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		if file, ok := data.(*fs.RemoveFile); ok {
+			remover.Send(file)
+			return
+		}
+		root.Reply(data)
+	})), true)
+
+	stack.Bind(fs.FileWriterWith(nil, recorder), true)
+	stack.Bind(tail, true)
 	return stack
 }
 
-// PackageWatcher generates a fs.Watch tasker which given a valid package name will retrieve the package directory and
-// those of its dependencies and watch it for changes, you can supply a validator function to filter out what path you
-// prefer to watch or not to
-func PackageWatcher(packageName string, vx assets.PathValidator) (flux.Reactor, error) {
-	pkg, err := assets.GetPackageLists(packageName)
+// JSMultiBuildConfig provides a configuration for JSMultiBuildLauncher
+type JSMultiBuildConfig struct {
+	Entries      []JSEntry
+	Folder       string    //Folder represents the path to be added to the name of where to store the files
+	RuntimeName  string    // Optional: RuntimeName is the output name of the shared runtime chunk, defaults to runtime
+	ManifestName string    // Optional: ManifestName is the AssetManifest file name within the Folder, defaults to assets.json
+	Fingerprint  bool      // Optional: if true, adds a content hash to the output names of each chunk and removes the files they replace
+	Options      JSOptions // Optional: build options shared by all the entries
+
+	Logger  logs.Logger      // Optional: Logger receives the build status messages, defaults to the global logs logger
+	Metrics metrics.Recorder // Optional: Metrics records the build counts, durations and spans, defaults to the global metrics recorder
+}
+
+// JSMultiBuildLauncher returns a Task generator that builds all the entries in the config in one session and on every
+// reception of signals sends off a FileWrite for the runtime chunk, each entry and their js.map files, ending with the
+// AssetManifest file which maps the chunk names eg. runtime.js and app.js to their files. The runtime chunk must be
+// loaded before any entry. With Fingerprint set a *fs.RemoveFile follows for every hashed file of the previous
+// manifest replaced by the build
+func JSMultiBuildLauncher(config JSMultiBuildConfig) flux.Reactor {
+	return mustReactor(NewJSMultiBuildLauncher(config))
+}
+
+// NewJSMultiBuildLauncher returns a JSMultiBuildLauncher task or a *ValidationError if the config is invalid
+func NewJSMultiBuildLauncher(config JSMultiBuildConfig) (flux.Reactor, error) {
+	if config.RuntimeName == "" {
+		config.RuntimeName = "runtime"
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.ManifestName == "" {
+		config.ManifestName = "assets.json"
+	}
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		id := fs.IDOf(data)
+		session := NewJSSessionWith(config.Options)
+
+		start := time.Now()
+		done := metrics.StartBuild(config.Metrics, id, "JSMultiBuildLauncher")
+		bundle, err := session.BuildEntries(config.Entries, config.RuntimeName)
+
+		if err != nil {
+			logs.Error(config.Logger, "js build failed", logs.Task("JSMultiBuildLauncher"), logs.Duration(time.Since(start)), logs.Err(err))
+			done(err)
+			root.ReplyError(err)
+			return
+		}
+
+		done(nil)
+
+		logs.Info(config.Logger, "js build finished", logs.Task("JSMultiBuildLauncher"), logs.Duration(time.Since(start)), logs.F("entries", len(bundle.Entries)))
+
+		//the manifest on disk gives the files replaced by this build
+		manifestFile := filepath.Join(config.Folder, config.ManifestName)
+		manifest, err := ReadAssetManifest(manifestFile)
+
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+
+		var stale []string
+
+		chunks := append([]*JSChunk{bundle.Runtime}, bundle.Entries...)
+
+		for _, chunk := range chunks {
+			base, jsdata := chunk.Name, chunk.JS.Bytes()
+
+			if config.Fingerprint {
+				base, jsdata = fingerprintJS(chunk.Name, jsdata)
+			}
+
+			jsfile := fmt.Sprintf("%s.js", base)
+			root.Reply(&fs.FileWrite{Data: jsdata, Path: filepath.Join(config.Folder, jsfile), ID: id})
+
+			if old := manifest.Replace(fmt.Sprintf("%s.js", chunk.Name), jsfile, jsdata); old != "" {
+				stale = append(stale, old)
+			}
+
+			if session.SourceMap == SourceMapFile {
+				jsmapfile := fmt.Sprintf("%s.js.map", base)
+				root.Reply(&fs.FileWrite{Data: chunk.JSMap.Bytes(), Path: filepath.Join(config.Folder, jsmapfile), ID: id})
+
+				if old := manifest.Replace(fmt.Sprintf("%s.js.map", chunk.Name), jsmapfile, chunk.JSMap.Bytes()); old != "" {
+					stale = append(stale, old)
+				}
+			}
+		}
+
+		mdata, err := json.MarshalIndent(manifest, "", "  ")
+
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+
+		root.Reply(&fs.FileWrite{Data: mdata, Path: manifestFile, ID: id})
+
+		if !config.Fingerprint {
+			return
+		}
+
+		for _, file := range stale {
+			root.Reply(&fs.RemoveFile{Path: filepath.Join(config.Folder, file), ID: id})
+		}
+	})), nil
+}
+
+// JSMultiLauncher returns a reactor that on receiving a signal builds all the gopherjs entries as giving in the config and writes them out using a FileWriter,
+// removing the stale fingerprinted files with a FileRemover
+func JSMultiLauncher(config JSMultiBuildConfig) flux.Reactor {
+	return mustReactor(NewJSMultiLauncher(config))
+}
+
+// NewJSMultiLauncher returns a JSMultiLauncher task or a *ValidationError if the config is invalid
+func NewJSMultiLauncher(config JSMultiBuildConfig) (flux.Reactor, error) {
+	builder, err := NewJSMultiBuildLauncher(config)
+
 	if err != nil {
 		return nil, err
 	}
 
-	return fs.WatchSet(fs.WatchSetConfig{
-		Path:      pkg,
-		Validator: vx,
-	}), nil
+	return writeOutputs(builder, config.Metrics), nil
+}
+
+// PackageWatcher generates a fs.Watch tasker which given a valid package name will resolve the files of the package
+// and those of its local dependencies and watch them for changes, you can supply a validator function to filter out
+// what path you prefer to watch or not to, see PackageWatcherWith for the resolution and the git modes
+func PackageWatcher(packageName string, vx assets.PathValidator) (flux.Reactor, error) {
+	return PackageWatcherWith(PackageWatchConfig{Package: packageName, Validator: vx})
 }
 
 // RenderFile repesents a render requested used by ByteRender for handling rendering
 type RenderFile struct {
 	Path string
 	Data []byte
+	ID   string                 // Optional: correlation ID of the change which caused the render
+	Meta map[string]interface{} // Optional: metadata of the file such as its parsed front matter
+	TOC  []*Heading             // Optional: headings of the rendered file when added by the TOC task
+
+	Permalink string // Optional: url of the page when rendered by Site
+}
+
+// Correlation returns the correlation ID of the render
+func (r *RenderFile) Correlation() string {
+	return r.ID
+}
+
+// withData returns a copy of the render with the data replaced, keeping the other fields for the next stages
+func (r *RenderFile) withData(data []byte) *RenderFile {
+	next := *r
+	next.Data = data
+	return &next
 }
 
 // ErrNotRenderFile is returned when a type is not a *RenderFile
@@ -358,14 +699,19 @@ type RenderMux func([]byte) []byte
 
 // ByteRenderer provides a baseline worker for building rendering tasks eg markdown. It expects to receive a *RenderFile and then it returns another *RenderFile containing the outputed rendered data with the path from the previous RenderFile,this allows chaining with other ByteRenderers
 func ByteRenderer(fx RenderMux) flux.Reactor {
+	return mustReactor(NewByteRenderer(fx))
+}
+
+// NewByteRenderer returns a ByteRenderer task or ErrNilRenderMux if the RenderMux is nil
+func NewByteRenderer(fx RenderMux) (flux.Reactor, error) {
 	if fx == nil {
-		panic("RenderMux cant be nil for ByteRender")
+		return nil, ErrNilRenderMux
 	}
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if databytes, ok := data.(*RenderFile); ok {
-			root.Reply(&RenderFile{Path: databytes.Path, Data: fx(databytes.Data)})
+			root.Reply(databytes.withData(fx(databytes.Data)))
 		}
-	}))
+	})), nil
 }
 
 // BlackFriday returns a reactor which expects a RenderFile whoes data gets converted into markdown and returns a RenderedFile as output signal, it builds ontop of ByteRenderer
@@ -387,7 +733,7 @@ func BlackMonday() flux.Reactor {
 func FileRead2RenderFile() flux.Reactor {
 	return flux.FlatSimple(func(root flux.Reactor, data interface{}) {
 		if fr, ok := data.(*fs.FileRead); ok {
-			root.Reply(&RenderFile{Path: fr.Path, Data: fr.Data})
+			root.Reply(&RenderFile{Path: fr.Path, Data: fr.Data, ID: fr.ID})
 		}
 	})
 }
@@ -396,7 +742,7 @@ func FileRead2RenderFile() flux.Reactor {
 func FileWrite2RenderFile() flux.Reactor {
 	return flux.FlatSimple(func(root flux.Reactor, data interface{}) {
 		if fr, ok := data.(*fs.FileWrite); ok {
-			root.Reply(&RenderFile{Path: fr.Path, Data: fr.Data})
+			root.Reply(&RenderFile{Path: fr.Path, Data: fr.Data, ID: fr.ID, Meta: fr.Meta})
 		}
 	})
 }
@@ -405,7 +751,7 @@ func FileWrite2RenderFile() flux.Reactor {
 func RenderFile2FileWrite() flux.Reactor {
 	return flux.FlatSimple(func(root flux.Reactor, data interface{}) {
 		if fr, ok := data.(*RenderFile); ok {
-			root.Reply(&fs.FileWrite{Path: fr.Path, Data: fr.Data})
+			root.Reply(&fs.FileWrite{Path: fr.Path, Data: fr.Data, ID: fr.ID, Meta: fr.Meta})
 		}
 	})
 }
@@ -428,71 +774,197 @@ func MutateFileWrite(fx FileWriteMutator) flux.Reactor {
 }
 
 // MarkConfig provides a config for turning inputs from file through a markdown preprocessor then
-// save this files with an extension change into the given folder
+// save this files with an extension change into the given folder. Yaml front matter delimited by --- lines or toml
+// front matter delimited by +++ lines is stripped off the files and set as the Meta of the writes, a slug key replaces
+// the output file name and files with draft: true are skipped unless Drafts is set
 type MarkConfig struct {
-	SaveDir     string                          // optional: path to save output files into but if empty,it uses the files own path original path
-	Ext         string                          //Optional: supply it incase you wish to change the file extension, else use a .md extension
-	Sanitize    bool                            //Optional: if true will combine markdown and bluemonday together
-	PathMux     func(MarkConfig, string) string //Optional: if present will be used to generate the file path which gets its extension swapped and is used as the output filepath
+	SaveDir     string                                      // optional: path to save output files into but if empty,it uses the files own path original path
+	Ext         string                                      //Optional: supply it incase you wish to change the file extension, else use a .md extension
+	Sanitize    bool                                        //Optional: if true will combine markdown and bluemonday together
+	Policy      PolicyConfig                                //Optional: sanitizing policy used when Sanitize is set, defaults to the bluemonday UGCPolicy
+	Markdown    MarkdownOptions                             //Optional: selects the markdown engine and its extensions, defaults to blackfriday with its common extensions
+	Highlight   HighlightConfig                             //Optional: if Enabled the fenced code blocks are highlighted after sanitizing, see Highlight
+	TOC         TOCConfig                                   //Optional: if Enabled the headings get ids and anchors and the table of contents is generated, see TOC
+	Links       LinkConfig                                  //Optional: if Rewrite is set the links to markdown files point to their output files
+	Drafts      bool                                        //Optional: if true files marked with draft: true are rendered instead of skipped
+	Layout      LayoutConfig                                //Optional: if its TemplateDir is set the rendered markdown is executed with the layouts before being written
+	PathMux     func(MarkConfig, string) string             //Optional: if present will be used to generate the file path which gets its extension swapped and is used as the output filepath
+	OutputPath  func(string, map[string]interface{}) string //Optional: if present returns the final output path of the file at the path with the given front matter, replacing PathMux, slugs and Ext
 	BeforeWrite FileWriteMutator
+
+	meta map[string]interface{}
+}
+
+// FileMeta returns the front matter of the file whose path is passed to PathMux, it is nil outside of PathMux
+func (m MarkConfig) FileMeta() map[string]interface{} {
+	return m.meta
 }
 
 // MarkFriday combines a fs.FilReader with a markdown processor which then pipes into a fs.FileWriter to save the output
 func MarkFriday(m MarkConfig) flux.Reactor {
+	return mustReactor(NewMarkFriday(m))
+}
+
+// NewMarkFriday returns a MarkFriday task or a *ValidationError if the config is invalid
+func NewMarkFriday(m MarkConfig) (flux.Reactor, error) {
+	return newMarkFriday(m, streamHooks{})
+}
+
+// newMarkFriday returns a MarkFriday task recording the links of the files with the checker, their outputs with the
+// tracker and passing the rendered files to the observer of the hooks if any
+func newMarkFriday(m MarkConfig, hooks streamHooks) (flux.Reactor, error) {
+	checker, tracker := hooks.checker, hooks.tracker
+
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
 	if m.Ext == "" {
 		m.Ext = ".md"
 	}
 
-	var markdown flux.Reactor
+	policy, err := markPolicy(m.Sanitize, m.Policy, m.Highlight)
 
-	if m.Sanitize {
-		markdown = BlackMonday()
-	} else {
-		markdown = BlackFriday()
+	if err != nil {
+		return nil, err
 	}
 
-	reader := fs.FileReader()
+	render, err := markdownRenderer(m.Markdown, policy)
 
-	// reader.React(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
-	// 	log.Printf("reader %s", data)
-	// }), true)
+	if err != nil {
+		return nil, err
+	}
 
-	writer := fs.FileWriter(func(path string) string {
-		var dir string
+	markdown := ByteRenderer(render)
 
-		if m.PathMux != nil {
-			dir = m.PathMux(m, path)
-		} else {
-			//get the current directory of the path
-			cdir := filepath.Dir(path)
+	var highlight flux.Reactor
 
-			//if we have a preset folder replace it
-			if m.SaveDir != "" {
-				cdir = m.SaveDir
-			}
+	if m.Highlight.Enabled {
+		if highlight, err = Highlight(cssPath(m.Highlight, m.SaveDir)); err != nil {
+			return nil, err
+		}
+	}
 
-			// strip out the directory from the path and only use the base name
-			base := filepath.Base(path)
+	var toc flux.Reactor
 
-			//combine with the dir for the final path
-			dir = filepath.Join(cdir, base)
+	if m.TOC.Enabled {
+		if toc, err = TOC(m.TOC); err != nil {
+			return nil, err
 		}
+	}
 
-		//grab our own extension
-		ext := strings.Replace(m.Ext, ".", "", -1)
+	var layout flux.Reactor
 
-		//strip off the extension and add ours
-		return strings.Replace(dir, filepath.Ext(dir), fmt.Sprintf(".%s", ext), -1)
-	})
+	if m.Layout.TemplateDir != "" {
+		if layout, err = Layout(m.Layout); err != nil {
+			return nil, err
+		}
+	}
+
+	reader := fs.FileReader()
+
+	// reader.React(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+	// 	log.Printf("reader %s", data)
+	// }), true)
+
+	drafts := flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		if rf, ok := data.(*RenderFile); ok && (m.Drafts || !IsDraft(rf.Meta)) {
+			root.Reply(rf)
+		}
+	}))
 
 	stack := flux.ReactStack(reader)
 	stack.Bind(FileRead2RenderFile(), true)
+	stack.Bind(FrontMatter(), true)
+	stack.Bind(drafts, true)
 	stack.Bind(markdown, true)
+
+	if highlight != nil {
+		stack.Bind(highlight, true)
+	}
+
+	if toc != nil {
+		stack.Bind(toc, true)
+	}
+
+	if m.Links.Rewrite || checker != nil {
+		stack.Bind(linkStage(m, checker), true)
+	}
+
+	if hooks.observer != nil {
+		stack.Bind(flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+			if rf, ok := data.(*RenderFile); ok {
+				root.Reply(hooks.observer.rendered(rf))
+			}
+		})), true)
+	}
+
+	if layout != nil {
+		stack.Bind(layout, true)
+	}
+
 	stack.Bind(RenderFile2FileWrite(), true)
 	stack.Bind(MutateFileWrite(m.BeforeWrite), true)
-	stack.Bind(writer, true)
+	stack.Bind(flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		w, ok := data.(*fs.FileWrite)
 
-	return stack
+		if !ok {
+			return
+		}
+
+		source := w.Path
+		w.Path = markPath(m, source, w.Meta)
+
+		if tracker != nil {
+			if err := tracker.record(source, w.Path, w.ID); err != nil {
+				root.ReplyError(err)
+			}
+		}
+
+		root.Reply(w)
+	})), true)
+	stack.Bind(fs.FileWriter(nil), true)
+
+	return stack, nil
+}
+
+// markPath returns the output path of the markdown file at the path with the given front matter
+func markPath(m MarkConfig, path string, meta map[string]interface{}) string {
+	if m.OutputPath != nil {
+		return m.OutputPath(path, meta)
+	}
+
+	var dir string
+
+	if m.PathMux != nil {
+		m.meta = meta
+		dir = m.PathMux(m, path)
+	} else {
+		//get the current directory of the path
+		cdir := filepath.Dir(path)
+
+		//if we have a preset folder replace it
+		if m.SaveDir != "" {
+			cdir = m.SaveDir
+		}
+
+		// strip out the directory from the path and only use the base name
+		base := filepath.Base(path)
+
+		//combine with the dir for the final path
+		dir = filepath.Join(cdir, base)
+	}
+
+	//the slug of the front matter replaces the file name
+	if slug := MetaString(meta, "slug"); slug != "" {
+		dir = filepath.Join(filepath.Dir(dir), filepath.Base(slug)+filepath.Ext(dir))
+	}
+
+	//grab our own extension
+	ext := strings.Replace(m.Ext, ".", "", -1)
+
+	//strip off the extension and add ours
+	return strings.Replace(dir, filepath.Ext(dir), fmt.Sprintf(".%s", ext), -1)
 }
 
 // MarkStreamConfig defines the configuration to be recieved by MarkFridayStream for auto-streaming markdown files
@@ -501,18 +973,74 @@ type MarkStreamConfig struct {
 	SaveDir     string
 	Ext         string
 	Sanitize    bool
+	Policy      PolicyConfig
+	Markdown    MarkdownOptions
+	Highlight   HighlightConfig
+	TOC         TOCConfig
+	Links       LinkConfig
+	Drafts      bool
+	Layout      LayoutConfig
+	Cleanup     bool
+	Incremental bool
+	StateFile   string
 	Validator   assets.PathValidator
 	Mux         assets.PathMux
+	OutputPath  func(string, map[string]interface{}) string // Optional: returns the output path of the file at the path relative to InputDir with the given front matter
 	BeforeWrite FileWriteMutator
 }
 
+// stateFile returns the path of the state file tracking the outputs of the stream, defaulting to .markfriday.json
+// within the SaveDir or the InputDir when there is none
+func (m MarkStreamConfig) stateFile() string {
+	if m.StateFile != "" {
+		return m.StateFile
+	}
+
+	if m.SaveDir != "" {
+		return filepath.Join(m.SaveDir, ".markfriday.json")
+	}
+
+	return filepath.Join(m.InputDir, ".markfriday.json")
+}
+
 // MarkFridayStream returns a flux.Reactor that takes the given config and generates a markdown auto-converter, when
-// it recieves any signals,it will stream down each file and convert the markdown input and save into the desired output path
+// it recieves any signals,it will stream down each file and convert the markdown input and save into the desired output path.
+// With Cleanup set the stream records the output of each source in its StateFile, removes the outputs of sources
+// which were deleted or became drafts at the start of each run and the previous output of a source whose output path
+// changed eg. through a new slug, replying the *fs.RemoveFile of each removal.
+// With Incremental set a run only renders the sources which changed since they were last rendered, found by comparing
+// their modification time, size and hash with the StateFile or named by the fs.Watch event which signaled the run.
+// A change to the templates of the Layout, its policy file or the render options renders every page again, as does a
+// new source or a changed slug when links are rewritten. Incremental runs can not check links
 func MarkFridayStream(m MarkStreamConfig) (flux.Reactor, error) {
+	return markFridayStream(m, nil)
+}
+
+// markFridayStream returns a MarkFridayStream passing the rendered files and runs to the observer if not nil
+func markFridayStream(m MarkStreamConfig, observer streamObserver) (flux.Reactor, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	if m.Cleanup || m.Incremental {
+		//the state file is never rendered even when it sits within the InputDir
+		state, _ := filepath.Abs(m.stateFile())
+		validator := m.Validator
+
+		m.Validator = func(path string, info os.FileInfo) bool {
+			if abs, _ := filepath.Abs(path); abs == state {
+				return false
+			}
+			return validator == nil || validator(path, info)
+		}
+	}
+
+	//the paths carry the correlation ID of the run for the link checks, outputs and metrics of the stream
 	streamer, err := fs.StreamListings(fs.ListingConfig{
 		Path:      m.InputDir,
 		Validator: m.Validator,
 		Mux:       m.Mux,
+		Correlate: true,
 	})
 
 	if err != nil {
@@ -521,11 +1049,70 @@ func MarkFridayStream(m MarkStreamConfig) (flux.Reactor, error) {
 
 	absPath, _ := filepath.Abs(m.InputDir)
 
-	markdown := MarkFriday(MarkConfig{
+	//the tail replies the link reports, removals and observer writes of the stream
+	tail := flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+		root.Reply(data)
+	})
+
+	var checker *linkChecker
+
+	if m.Links.Check {
+		checker = &linkChecker{config: m.Links, reporter: tail}
+	}
+
+	var tracker *outputTracker
+
+	if m.Cleanup || m.Incremental {
+		if tracker, err = newOutputTracker(m.stateFile(), m.Cleanup, m.Incremental); err != nil {
+			return nil, err
+		}
+
+		tracker.remover.React(func(_ flux.Reactor, err error, data interface{}) {
+			if err != nil {
+				tail.SendError(err)
+				return
+			}
+			tail.Send(data)
+		}, true)
+	}
+
+	if observer != nil {
+		observer.writer().React(func(_ flux.Reactor, err error, data interface{}) {
+			if err != nil {
+				tail.SendError(err)
+				return
+			}
+			tail.Send(data)
+		}, true)
+	}
+
+	var outputPath func(string, map[string]interface{}) string
+
+	if m.OutputPath != nil {
+		outputPath = func(path string, meta map[string]interface{}) string {
+			abs, _ := filepath.Abs(path)
+			rel, _ := filepath.Rel(absPath, abs)
+			return m.OutputPath(rel, meta)
+		}
+	}
+
+	markdown, err := newMarkFriday(MarkConfig{
 		SaveDir:     m.SaveDir,
 		Ext:         m.Ext,
 		Sanitize:    m.Sanitize,
+		Policy:      m.Policy,
+		Markdown:    m.Markdown,
+		Highlight:   m.Highlight,
+		TOC:         m.TOC,
+		Links:       m.Links,
+		Drafts:      m.Drafts,
+		Layout:      m.Layout,
 		BeforeWrite: m.BeforeWrite,
+		OutputPath:  outputPath,
 		PathMux: func(m MarkConfig, path string) string {
 			//we find the index of the absolute path we need to index
 			index := strings.Index(path, absPath)
@@ -545,10 +1132,27 @@ func MarkFridayStream(m MarkStreamConfig) (flux.Reactor, error) {
 
 			return filepath.Join(m.SaveDir, strings.Replace(path, base, "./", 1))
 		},
-	})
+	}, streamHooks{checker: checker, tracker: tracker, observer: observer})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if checker == nil && tracker == nil && observer == nil {
+		stack := flux.ReactStack(streamer)
+		stack.Bind(markdown, true)
+		return stack, nil
+	}
+
+	stack := flux.ReactStack(streamGate(m, streamHooks{checker: checker, tracker: tracker, observer: observer}))
+	stack.Bind(streamer, true)
+
+	if m.Incremental {
+		stack.Bind(changedSources(tracker), true)
+	}
 
-	stack := flux.ReactStack(streamer)
 	stack.Bind(markdown, true)
+	stack.Bind(tail, true)
 
 	return stack, nil
 }
@@ -572,3 +1176,31 @@ func GoFridayStream(m MarkStreamConfig) (flux.Reactor, error) {
 	}
 	return MarkFridayStream(m)
 }
+
+// correlation holds the correlation ID of the latest signal of a launcher, which is replied with the signal sent once
+// its process or commands were started
+type correlation struct {
+	rw sync.RWMutex
+	id string
+}
+
+func (c *correlation) set(id string) {
+	c.rw.Lock()
+	c.id = id
+	c.rw.Unlock()
+}
+
+func (c *correlation) get() string {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.id
+}
+
+// mustReactor panics with the error if any else returns the reactor, its used by the constructors which predate
+// their error returning variants
+func mustReactor(r flux.Reactor, err error) flux.Reactor {
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
@@ -0,0 +1,289 @@
+package builders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+)
+
+// DevServerConfig provides the configuration for a DevServer
+type DevServerConfig struct {
+	Addr   string // Addr is the address to listen on eg. :8080
+	Dir    string // Dir is the directory served over http
+	Prefix string // Optional: Prefix is the path for the live-reload endpoints, defaults to /__reactors
+
+	Logger logs.Logger // Optional: Logger receives the server status messages, defaults to the global logs logger
+}
+
+// DevMessage is pushed to the browsers connected to a DevServer, Type is one of reload, css or error
+type DevMessage struct {
+	Type    string   `json:"type"`
+	Path    string   `json:"path,omitempty"`
+	Overlay *Overlay `json:"overlay,omitempty"`
+}
+
+// devHub keeps the list of connected browser streams
+type devHub struct {
+	rw      sync.RWMutex
+	clients map[chan []byte]bool
+}
+
+func (d *devHub) add() chan []byte {
+	client := make(chan []byte, 8)
+	d.rw.Lock()
+	d.clients[client] = true
+	d.rw.Unlock()
+	return client
+}
+
+func (d *devHub) remove(client chan []byte) {
+	d.rw.Lock()
+	delete(d.clients, client)
+	d.rw.Unlock()
+}
+
+func (d *devHub) send(msg *DevMessage) {
+	data, err := json.Marshal(msg)
+
+	if err != nil {
+		return
+	}
+
+	d.rw.RLock()
+	defer d.rw.RUnlock()
+
+	for client := range d.clients {
+		select {
+		case client <- data:
+		default:
+			//the browser is not keeping up, skip this message for it
+		}
+	}
+}
+
+// DevServer returns a reactor that serves the config Dir over http, injecting a live-reload script into html pages.
+// When it receives a *fs.FileWrite, connected browsers reload or hot-swap stylesheets for .css files, when it receives
+// an error they display it as an overlay using the *DiagnosticError overlay if available. All signals are passed on
+func DevServer(config DevServerConfig) flux.Reactor {
+	return mustReactor(NewDevServer(config))
+}
+
+// NewDevServer returns a DevServer task or a *ValidationError if the config is invalid
+func NewDevServer(config DevServerConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Dir == "" {
+		config.Dir = "."
+	}
+
+	if config.Prefix == "" {
+		config.Prefix = "/__reactors"
+	}
+
+	hub := &devHub{clients: make(map[chan []byte]bool)}
+
+	mo := flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			logs.Debug(config.Logger, "sending error overlay", logs.Task("DevServer"), logs.Err(err))
+			hub.send(&DevMessage{Type: "error", Overlay: errorOverlay(err)})
+			root.ReplyError(err)
+			return
+		}
+
+		if fw, ok := data.(*fs.FileWrite); ok {
+			msg := devMessage(config.Dir, fw.Path)
+			logs.Debug(config.Logger, "sending "+msg.Type, logs.Task("DevServer"), logs.Path(fw.Path))
+			hub.send(msg)
+		}
+
+		root.Reply(data)
+	})
+
+	server := &http.Server{Addr: config.Addr, Handler: newDevHandler(config, hub)}
+
+	flux.GoDefer("DevServer", func() {
+		logs.Info(config.Logger, "dev server listening", logs.Task("DevServer"), logs.Path(config.Dir), logs.F("addr", config.Addr))
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logs.Error(config.Logger, "dev server failed", logs.Task("DevServer"), logs.F("addr", config.Addr), logs.Err(err))
+			mo.ReplyError(err)
+		}
+	})
+
+	flux.GoDefer("DevServer.Close", func() {
+		<-mo.CloseNotify()
+		server.Close()
+	})
+
+	return mo, nil
+}
+
+// errorOverlay returns the overlay for a *DiagnosticError or a generic overlay of the error message
+func errorOverlay(err error) *Overlay {
+	if derr, ok := err.(*DiagnosticError); ok {
+		return derr.Overlay()
+	}
+
+	return (&DiagnosticError{
+		Package:     "build",
+		Diagnostics: []Diagnostic{{Message: err.Error()}},
+		Err:         err,
+	}).Overlay()
+}
+
+// devMessage returns the message to send for a written file, stylesheets within the served dir are hot-swapped
+func devMessage(dir, file string) *DevMessage {
+	if filepath.Ext(file) != ".css" {
+		return &DevMessage{Type: "reload"}
+	}
+
+	absDir, _ := filepath.Abs(dir)
+	absFile, _ := filepath.Abs(file)
+
+	rel, err := filepath.Rel(absDir, absFile)
+
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return &DevMessage{Type: "reload"}
+	}
+
+	return &DevMessage{Type: "css", Path: "/" + filepath.ToSlash(rel)}
+}
+
+// newDevHandler returns the http.Handler for the DevServer which serves the live-reload endpoints and the directory
+func newDevHandler(config DevServerConfig, hub *devHub) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc(config.Prefix+"/client.js", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/javascript")
+		fmt.Fprintf(w, devClientScript, config.Prefix+"/events")
+	})
+
+	mux.HandleFunc(config.Prefix+"/events", func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+
+		if !ok {
+			http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		flusher.Flush()
+
+		client := hub.add()
+		defer hub.remove(client)
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case msg := <-client:
+				fmt.Fprintf(w, "data: %s\n\n", msg)
+				flusher.Flush()
+			}
+		}
+	})
+
+	files := http.FileServer(http.Dir(config.Dir))
+	script := []byte(fmt.Sprintf(`<script src="%s/client.js"></script>`, config.Prefix))
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		file := filepath.Join(config.Dir, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+
+		if stat, err := os.Stat(file); err == nil && stat.IsDir() {
+			file = filepath.Join(file, "index.html")
+		}
+
+		if ext := filepath.Ext(file); ext != ".html" && ext != ".htm" {
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		data, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			files.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(injectScript(data, script))
+	})
+
+	return mux
+}
+
+// injectScript adds the script before the closing body tag of the page or at its end if none is found
+func injectScript(page, script []byte) []byte {
+	index := bytes.LastIndex(bytes.ToLower(page), []byte("</body>"))
+
+	if index == -1 {
+		return append(append([]byte{}, page...), script...)
+	}
+
+	var out []byte
+	out = append(out, page[:index]...)
+	out = append(out, script...)
+	out = append(out, page[index:]...)
+	return out
+}
+
+// devClientScript is the live-reload browser client, it expects the events endpoint as its only format value
+const devClientScript = `(function() {
+	var overlay;
+	var source = new EventSource("%s");
+
+	function showOverlay(payload) {
+		if (!overlay) {
+			overlay = document.createElement("div");
+			overlay.style.cssText = "position:fixed;top:0;left:0;right:0;bottom:0;z-index:99999;overflow:auto;padding:2em;background:rgba(20,20,20,0.92);color:#f66;font-family:monospace;";
+			document.body.appendChild(overlay);
+		}
+		overlay.innerHTML = payload.html;
+	}
+
+	function swapCSS(path) {
+		var links = document.querySelectorAll("link[rel=stylesheet]");
+		var found = false;
+		for (var i = 0; i < links.length; i++) {
+			var href = links[i].getAttribute("href") || "";
+			if (href.split("?")[0].replace(/^\.?\//, "") === path.replace(/^\//, "")) {
+				links[i].setAttribute("href", href.split("?")[0] + "?v=" + Date.now());
+				found = true;
+			}
+		}
+		if (!found) {
+			location.reload();
+		}
+	}
+
+	source.onmessage = function(event) {
+		var msg = JSON.parse(event.data);
+		switch (msg.type) {
+		case "css":
+			swapCSS(msg.path);
+			break;
+		case "error":
+			showOverlay(msg.overlay);
+			break;
+		default:
+			location.reload();
+		}
+	};
+})();
+`
@@ -0,0 +1,50 @@
+package builders
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestDevHandlerInjection(t *testing.T) {
+	hub := &devHub{clients: make(map[chan []byte]bool)}
+	handler := newDevHandler(DevServerConfig{Dir: "../fixtures/templates", Prefix: "/__reactors"}, hub)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/base.html", nil))
+
+	body, _ := ioutil.ReadAll(rec.Body)
+
+	if !strings.Contains(string(body), `<script src="/__reactors/client.js"></script>`) {
+		flux.FatalFailed(t, "Expected live-reload script in page: %s", body)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/__reactors/client.js", nil))
+
+	if !strings.Contains(rec.Body.String(), `new EventSource("/__reactors/events")`) {
+		flux.FatalFailed(t, "Expected events endpoint in client script: %s", rec.Body.String())
+	}
+
+	flux.LogPassed(t, "Successfully injected live-reload script")
+}
+
+func TestDevMessages(t *testing.T) {
+	if msg := devMessage("../fixtures", "../fixtures/css/site.css"); msg.Type != "css" || msg.Path != "/css/site.css" {
+		flux.FatalFailed(t, "Expected css hot-swap message: %+s", msg)
+	}
+
+	if msg := devMessage("../fixtures", "../fixtures/templates/base.html"); msg.Type != "reload" {
+		flux.FatalFailed(t, "Expected reload message: %+s", msg)
+	}
+
+	if overlay := errorOverlay(errors.New("main.go:3:1: bad")); !strings.Contains(overlay.HTML, "bad") {
+		flux.FatalFailed(t, "Expected error in overlay: %s", overlay.HTML)
+	}
+
+	flux.LogPassed(t, "Successfully built dev messages")
+}
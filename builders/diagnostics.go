@@ -0,0 +1,140 @@
+package builders
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/types"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gopherjs/gopherjs/compiler"
+)
+
+// Diagnostic describes a single compile error found in a build
+type Diagnostic struct {
+	Package string `json:"package"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Message string `json:"message"`
+}
+
+// String returns the diagnostic in the file:line:column: message format of the go tools
+func (d Diagnostic) String() string {
+	if d.File == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// DiagnosticError is returned by JSSession builds when the compilation fails, it contains the parsed diagnostics
+// along with the original error
+type DiagnosticError struct {
+	Package     string
+	Diagnostics []Diagnostic
+	Err         error
+}
+
+// Error returns all the diagnostics seperated by newlines
+func (d *DiagnosticError) Error() string {
+	var lines []string
+
+	for _, diag := range d.Diagnostics {
+		lines = append(lines, diag.String())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Unwrap returns the original build error
+func (d *DiagnosticError) Unwrap() error {
+	return d.Err
+}
+
+// fileLineCol matches errors in the file:line:column: message or file:line: message format
+var fileLineCol = regexp.MustCompile(`^(.+?\.go):(\d+)(?::(\d+))?:\s*(.*)$`)
+
+// Diagnose turns the error returned from building the given package into a *DiagnosticError, it understands the
+// type-check errors and error lists of the go tools and gopherjs compiler, falling back to parsing the error lines
+func Diagnose(pkg string, err error) *DiagnosticError {
+	if derr, ok := err.(*DiagnosticError); ok {
+		return derr
+	}
+
+	return &DiagnosticError{
+		Package:     pkg,
+		Diagnostics: diagnose(pkg, err),
+		Err:         err,
+	}
+}
+
+func diagnose(pkg string, err error) []Diagnostic {
+	switch e := err.(type) {
+	case compiler.ErrorList:
+		var diags []Diagnostic
+		for _, item := range e {
+			diags = append(diags, diagnose(pkg, item)...)
+		}
+		return diags
+	case scanner.ErrorList:
+		var diags []Diagnostic
+		for _, item := range e {
+			diags = append(diags, diagnose(pkg, item)...)
+		}
+		return diags
+	case *scanner.Error:
+		return []Diagnostic{{Package: pkg, File: e.Pos.Filename, Line: e.Pos.Line, Column: e.Pos.Column, Message: e.Msg}}
+	case types.Error:
+		pos := e.Fset.Position(e.Pos)
+		return []Diagnostic{{Package: pkg, File: pos.Filename, Line: pos.Line, Column: pos.Column, Message: e.Msg}}
+	}
+
+	var diags []Diagnostic
+
+	for _, line := range strings.Split(strings.TrimSpace(err.Error()), "\n") {
+		match := fileLineCol.FindStringSubmatch(strings.TrimSpace(line))
+
+		if match == nil {
+			diags = append(diags, Diagnostic{Package: pkg, Message: line})
+			continue
+		}
+
+		lnum, _ := strconv.Atoi(match[2])
+		col, _ := strconv.Atoi(match[3])
+
+		diags = append(diags, Diagnostic{Package: pkg, File: match[1], Line: lnum, Column: col, Message: match[4]})
+	}
+
+	return diags
+}
+
+// Overlay represents the payload pushed to a browser by a dev server to display build errors over the page
+type Overlay struct {
+	Type        string       `json:"type"`
+	Title       string       `json:"title"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+	HTML        string       `json:"html"`
+}
+
+// Overlay returns the overlay payload for the diagnostics
+func (d *DiagnosticError) Overlay() *Overlay {
+	var body []string
+
+	body = append(body, fmt.Sprintf("<h2>Build failed: %s</h2>", html.EscapeString(d.Package)))
+	body = append(body, "<ul>")
+
+	for _, diag := range d.Diagnostics {
+		body = append(body, fmt.Sprintf("<li><code>%s</code></li>", html.EscapeString(diag.String())))
+	}
+
+	body = append(body, "</ul>")
+
+	return &Overlay{
+		Type:        "error",
+		Title:       fmt.Sprintf("Build failed: %s", d.Package),
+		Diagnostics: d.Diagnostics,
+		HTML:        strings.Join(body, "\n"),
+	}
+}
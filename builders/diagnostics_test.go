@@ -0,0 +1,50 @@
+package builders
+
+import (
+	"errors"
+	"go/scanner"
+	"go/token"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestDiagnoseErrorList(t *testing.T) {
+	var list scanner.ErrorList
+	list.Add(token.Position{Filename: "main.go", Line: 4, Column: 2}, "expected ';', found 'EOF'")
+
+	derr := Diagnose("github.com/influx6/reactors/builders/base", list)
+
+	if len(derr.Diagnostics) != 1 {
+		flux.FatalFailed(t, "Expected one diagnostic: %+s", derr.Diagnostics)
+	}
+
+	diag := derr.Diagnostics[0]
+	if diag.File != "main.go" || diag.Line != 4 || diag.Column != 2 {
+		flux.FatalFailed(t, "Unexpected diagnostic position: %+s", diag)
+	}
+
+	flux.LogPassed(t, "Successfully diagnosed error list: %s", derr)
+}
+
+func TestDiagnoseText(t *testing.T) {
+	err := errors.New("base/main.go:7:14: undefined: jss\nbase/main.go:9: missing return")
+
+	derr := Diagnose("main", err)
+
+	if len(derr.Diagnostics) != 2 || derr.Diagnostics[1].Line != 9 || derr.Diagnostics[0].Column != 14 {
+		flux.FatalFailed(t, "Unexpected diagnostics: %+s", derr.Diagnostics)
+	}
+
+	overlay := derr.Overlay()
+	if !strings.Contains(overlay.HTML, "undefined: jss") {
+		flux.FatalFailed(t, "Expected diagnostic in overlay: %s", overlay.HTML)
+	}
+
+	if !errors.Is(derr, err) {
+		flux.FatalFailed(t, "Expected DiagnosticError to unwrap into the build error")
+	}
+
+	flux.LogPassed(t, "Successfully diagnosed text errors: %s", derr)
+}
@@ -0,0 +1,190 @@
+package builders
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influx6/flux"
+	"gopkg.in/yaml.v2"
+)
+
+// frontMatterFormats maps the front matter delimiters to the decoder of their format
+var frontMatterFormats = map[string]func([]byte) (map[string]interface{}, error){
+	"---": decodeYAMLMeta,
+	"+++": decodeTOMLMeta,
+}
+
+// decodeYAMLMeta decodes yaml front matter into a map with string keys all the way down
+func decodeYAMLMeta(data []byte) (map[string]interface{}, error) {
+	var raw map[interface{}]interface{}
+
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	meta, _ := metaKeys(raw).(map[string]interface{})
+
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+
+	return meta, nil
+}
+
+// decodeTOMLMeta decodes toml front matter into a map
+func decodeTOMLMeta(data []byte) (map[string]interface{}, error) {
+	var meta = make(map[string]interface{})
+
+	if _, err := toml.Decode(string(data), &meta); err != nil {
+		return nil, err
+	}
+
+	return meta, nil
+}
+
+// metaKeys turns the map[interface{}]interface{} values produced by yaml into map[string]interface{}
+func metaKeys(v interface{}) interface{} {
+	switch vals := v.(type) {
+	case map[interface{}]interface{}:
+		mapped := make(map[string]interface{})
+		for key, val := range vals {
+			mapped[fmt.Sprintf("%v", key)] = metaKeys(val)
+		}
+		return mapped
+	case []interface{}:
+		for index, val := range vals {
+			vals[index] = metaKeys(val)
+		}
+	}
+	return v
+}
+
+// ParseFrontMatter splits the yaml front matter delimited by --- lines or the toml front matter delimited by +++ lines
+// from the start of the data, returning the decoded metadata and the remaining body. Data without front matter, or
+// whose front matter is unclosed or does not decode, is returned as the body with a nil map
+func ParseFrontMatter(data []byte) (map[string]interface{}, []byte) {
+	first, rest := splitLine(data)
+	delim := string(bytes.TrimRight(first, " \t\r"))
+
+	decode, ok := frontMatterFormats[delim]
+
+	if !ok || len(first) == len(data) {
+		return nil, data
+	}
+
+	var head []byte
+
+	for body := rest; len(body) > 0; {
+		line, next := splitLine(body)
+
+		if string(bytes.TrimRight(line, " \t\r")) == delim {
+			meta, err := decode(head)
+
+			//a leading --- may just be a horizontal rule, so undecodable front matter is left in the body
+			if err != nil {
+				return nil, data
+			}
+
+			return meta, next
+		}
+
+		head = rest[:len(rest)-len(next)]
+		body = next
+	}
+
+	return nil, data
+}
+
+// splitLine returns the first line of the data without its newline and the data after it
+func splitLine(data []byte) ([]byte, []byte) {
+	if index := bytes.IndexByte(data, '\n'); index != -1 {
+		return data[:index], data[index+1:]
+	}
+	return data, nil
+}
+
+// FrontMatter returns a task which strips the front matter off the *RenderFile it receives and replies a *RenderFile
+// of the body with the metadata in its Meta field, files without front matter pass through with an empty Meta
+func FrontMatter() flux.Reactor {
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		rf, ok := data.(*RenderFile)
+
+		if !ok {
+			return
+		}
+
+		meta, body := ParseFrontMatter(rf.Data)
+
+		if meta == nil {
+			meta = make(map[string]interface{})
+		}
+
+		for key, val := range rf.Meta {
+			if _, ok := meta[key]; !ok {
+				meta[key] = val
+			}
+		}
+
+		next := rf.withData(body)
+		next.Meta = meta
+		root.Reply(next)
+	}))
+}
+
+// IsDraft returns true if the metadata marks the file as a draft with draft: true
+func IsDraft(meta map[string]interface{}) bool {
+	draft, _ := meta["draft"].(bool)
+	return draft
+}
+
+// MetaString returns the string value of the key in the metadata or an empty string if it is missing or not a string
+func MetaString(meta map[string]interface{}, key string) string {
+	val, _ := meta[key].(string)
+	return val
+}
+
+// metaTimeLayouts are the formats tried for dates given as strings in front matter
+var metaTimeLayouts = []string{time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"}
+
+// MetaTime returns the time value of the key in the metadata, parsing strings in the RFC3339 or 2006-01-02 formats,
+// or the zero time if it is missing or invalid
+func MetaTime(meta map[string]interface{}, key string) time.Time {
+	switch val := meta[key].(type) {
+	case time.Time:
+		return val
+	case string:
+		for _, layout := range metaTimeLayouts {
+			if stamp, err := time.Parse(layout, val); err == nil {
+				return stamp
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// MetaStrings returns the strings of a list value of the key in the metadata, a single string is split on commas
+func MetaStrings(meta map[string]interface{}, key string) []string {
+	var vals []string
+
+	switch val := meta[key].(type) {
+	case []interface{}:
+		for _, item := range val {
+			if str, ok := item.(string); ok && str != "" {
+				vals = append(vals, str)
+			}
+		}
+	case []string:
+		vals = append(vals, val...)
+	case string:
+		for _, item := range strings.Split(val, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				vals = append(vals, item)
+			}
+		}
+	}
+
+	return vals
+}
@@ -0,0 +1,64 @@
+package builders
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestParseFrontMatter(t *testing.T) {
+	meta, body := ParseFrontMatter([]byte("---\ntitle: Hello\ndraft: true\ntags:\n  - go\nauthor:\n  name: ana\n---\n# Hello\n"))
+
+	if MetaString(meta, "title") != "Hello" || !IsDraft(meta) || string(body) != "# Hello\n" {
+		flux.FatalFailed(t, "Unexpected yaml front matter %+v with body %q", meta, body)
+	}
+
+	if author, ok := meta["author"].(map[string]interface{}); !ok || author["name"] != "ana" {
+		flux.FatalFailed(t, "Expected nested maps to have string keys: %+v", meta["author"])
+	}
+
+	meta, body = ParseFrontMatter([]byte("+++\r\ntitle = \"Hello\"\r\nslug = \"hi\"\r\n+++\r\nbody"))
+
+	if MetaString(meta, "slug") != "hi" || string(body) != "body" {
+		flux.FatalFailed(t, "Unexpected toml front matter %+v with body %q", meta, body)
+	}
+
+	if meta, body = ParseFrontMatter([]byte("# Plain\n---\n")); meta != nil || string(body) != "# Plain\n---\n" {
+		flux.FatalFailed(t, "Expected files without front matter to pass as is: %+v %q", meta, body)
+	}
+
+	if meta, body = ParseFrontMatter([]byte("---\ntitle: Hello\n# Hello\n")); meta != nil || string(body) != "---\ntitle: Hello\n# Hello\n" {
+		flux.FatalFailed(t, "Expected unclosed front matter to pass as is: %+v %q", meta, body)
+	}
+
+	if meta, body = ParseFrontMatter([]byte("---\nIntro\n\n---\n[x\n")); meta != nil || string(body) != "---\nIntro\n\n---\n[x\n" {
+		flux.FatalFailed(t, "Expected undecodable front matter to pass as is: %+v %q", meta, body)
+	}
+
+	flux.LogPassed(t, "Successfully parsed front matter")
+}
+
+func TestMarkPath(t *testing.T) {
+	m := MarkConfig{SaveDir: "out", Ext: ".html"}
+
+	if path := markPath(m, filepath.Join("docs", "intro.md"), nil); path != filepath.Join("out", "intro.html") {
+		flux.FatalFailed(t, "Unexpected output path %q", path)
+	}
+
+	meta := map[string]interface{}{"slug": "getting-started"}
+
+	if path := markPath(m, filepath.Join("docs", "intro.md"), meta); path != filepath.Join("out", "getting-started.html") {
+		flux.FatalFailed(t, "Expected the slug to replace the file name: %q", path)
+	}
+
+	m.PathMux = func(m MarkConfig, path string) string {
+		return filepath.Join("out", MetaString(m.FileMeta(), "section"), filepath.Base(path))
+	}
+
+	if path := markPath(m, "intro.md", map[string]interface{}{"section": "guide"}); path != filepath.Join("out", "guide", "intro.html") {
+		flux.FatalFailed(t, "Expected PathMux to receive the front matter: %q", path)
+	}
+
+	flux.LogPassed(t, "Successfully generated output paths")
+}
@@ -0,0 +1,265 @@
+package builders
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/assets"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+)
+
+// GitChangeConfig provides the configuration for the GitChanges task
+type GitChangeConfig struct {
+	Path      string               // Path is a file or directory within the git repository
+	Ref       string               // Ref is the git ref the working tree is compared against eg. HEAD~1 or origin/master
+	Validator assets.PathValidator // Optional: filters the changed files which are sent
+	Correlate bool                 // Optional: if true the paths of a signal are sent within an *fs.Envelope sharing its correlation ID or a new one
+}
+
+// gitCommand runs git with the args in the directory and returns its output
+func gitCommand(dir string, args ...string) ([]byte, error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return nil, fmt.Errorf("git %s failed: %s: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return out, nil
+}
+
+// gitPaths splits the NUL separated output of git into absolute paths within the root
+func gitPaths(root string, out []byte) []string {
+	var paths []string
+
+	for _, name := range strings.Split(string(out), "\x00") {
+		if name == "" {
+			continue
+		}
+		paths = append(paths, filepath.Join(root, filepath.FromSlash(name)))
+	}
+
+	return paths
+}
+
+// realPath returns the absolute path with its symlinks evaluated, so paths of a checkout reached through a symlink
+// match those reported by git and go list. The nearest existing parent is evaluated for paths which do not exist
+// anymore eg. removed files
+func realPath(path string) string {
+	abs, err := filepath.Abs(path)
+
+	if err != nil {
+		return path
+	}
+
+	if real, err := filepath.EvalSymlinks(abs); err == nil {
+		return real
+	}
+
+	parent := filepath.Dir(abs)
+
+	if parent == abs {
+		return abs
+	}
+
+	return filepath.Join(realPath(parent), filepath.Base(abs))
+}
+
+// GitRoot returns the top level directory of the git repository containing the path, with its symlinks evaluated
+func GitRoot(path string) (string, error) {
+	dir, err := filepath.Abs(path)
+
+	if err != nil {
+		return "", err
+	}
+
+	if stat, err := os.Stat(dir); err == nil && !stat.IsDir() {
+		dir = filepath.Dir(dir)
+	}
+
+	out, err := gitCommand(dir, "rev-parse", "--show-toplevel")
+
+	if err != nil {
+		return "", err
+	}
+
+	return realPath(strings.TrimSpace(string(out))), nil
+}
+
+// GitFiles returns the absolute paths of the files tracked or untracked but not ignored in the git repository root
+func GitFiles(root string) ([]string, error) {
+	out, err := gitCommand(root, "ls-files", "-z", "--cached", "--others", "--exclude-standard")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gitPaths(root, out), nil
+}
+
+// GitChangedSince returns the absolute paths of the files added, copied, modified or renamed in the working tree of the
+// git repository root since the ref, including the untracked files which are not ignored
+func GitChangedSince(root, ref string) ([]string, error) {
+	out, err := gitCommand(root, "diff", "--name-only", "-z", "--diff-filter=ACMR", ref, "--")
+
+	if err != nil {
+		return nil, err
+	}
+
+	untracked, err := gitCommand(root, "ls-files", "-z", "--others", "--exclude-standard")
+
+	if err != nil {
+		return nil, err
+	}
+
+	var seen = make(map[string]bool)
+	var files []string
+
+	for _, file := range append(gitPaths(root, out), gitPaths(root, untracked)...) {
+		if !seen[file] {
+			seen[file] = true
+			files = append(files, file)
+		}
+	}
+
+	return files, nil
+}
+
+// gitFileSet keeps the files of a git repository and their directories for filtering watched paths, the set is
+// reloaded when asked about an unknown path so new untracked files get picked up
+type gitFileSet struct {
+	root   string
+	rw     sync.RWMutex
+	files  map[string]bool
+	loaded time.Time
+}
+
+func newGitFileSet(root string) (*gitFileSet, error) {
+	set := &gitFileSet{root: realPath(root)}
+
+	if err := set.load(); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+func (g *gitFileSet) load() error {
+	files, err := GitFiles(g.root)
+
+	if err != nil {
+		return err
+	}
+
+	var set = map[string]bool{g.root: true}
+
+	for _, file := range files {
+		set[file] = true
+
+		//keep the directories leading to the file so directory listings are not filtered out
+		for dir := filepath.Dir(file); len(dir) > len(g.root) && !set[dir]; dir = filepath.Dir(dir) {
+			set[dir] = true
+		}
+	}
+
+	g.rw.Lock()
+	g.files = set
+	g.loaded = time.Now()
+	g.rw.Unlock()
+	return nil
+}
+
+// has returns true if the path is a file or directory of the repository, reloading the set at most once a second
+// for unknown paths. The symlinks of the path are evaluated as the set holds the real paths of the repository
+func (g *gitFileSet) has(path string) bool {
+	abs := realPath(path)
+
+	g.rw.RLock()
+	found, stale := g.files[abs], time.Since(g.loaded) > time.Second
+	g.rw.RUnlock()
+
+	if found || !stale {
+		return found
+	}
+
+	if err := g.load(); err != nil {
+		return false
+	}
+
+	g.rw.RLock()
+	defer g.rw.RUnlock()
+	return g.files[abs]
+}
+
+// validator returns a PathValidator which only passes the paths of the set that also pass the given validator if any
+func (g *gitFileSet) validator(vx assets.PathValidator) assets.PathValidator {
+	return func(path string, info os.FileInfo) bool {
+		if !g.has(path) {
+			return false
+		}
+
+		if vx != nil {
+			return vx(path, info)
+		}
+
+		return true
+	}
+}
+
+// GitChanges returns a one-shot task which on every signal sends the absolute path of each file changed in the git
+// repository of the config Path since the config Ref, which allows rebuilding only what changed eg. in CI. With
+// Correlate set the paths of a signal are sent within an *fs.Envelope sharing the correlation ID of the signal or a
+// new one
+func GitChanges(config GitChangeConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	root, err := GitRoot(config.Path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gitChanges(root, config.Ref, config.Correlate, func(file string) bool {
+		return config.Validator == nil || config.Validator(file, nil)
+	}), nil
+}
+
+// gitChanges returns the GitChanges task for the repository root sending the changed files passing the filter
+func gitChanges(root, ref string, correlate bool, filter func(string) bool) flux.Reactor {
+	return flux.Reactive(flux.SimpleMuxer(func(r flux.Reactor, data interface{}) {
+		files, err := GitChangedSince(root, ref)
+
+		if err != nil {
+			r.ReplyError(err)
+			return
+		}
+
+		var id string
+
+		if correlate {
+			if id = fs.IDOf(data); id == "" {
+				id = fs.NewID()
+			}
+		}
+
+		for _, file := range files {
+			if filter(file) {
+				r.Reply(fs.Wrap(id, file))
+			}
+		}
+	}))
+}
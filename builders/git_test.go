@@ -0,0 +1,167 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+// gitFixture creates a git repository with a committed app.go, an untracked new.go and an ignored debug.log
+func gitFixture(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "reactors-git")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	files := map[string]string{
+		"app.go":     "package app\n",
+		".gitignore": "*.log\n",
+	}
+
+	for name, content := range files {
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	for _, args := range [][]string{
+		{"init", "-q"},
+		{"add", "."},
+		{"-c", "user.name=reactors", "-c", "user.email=reactors@localhost", "commit", "-q", "-m", "init"},
+	} {
+		if _, err := gitCommand(dir, args...); err != nil {
+			os.RemoveAll(dir)
+			flux.FatalFailed(t, "Unable to setup git fixture: %s", err)
+		}
+	}
+
+	ioutil.WriteFile(filepath.Join(dir, "new.go"), []byte("package app\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "debug.log"), []byte("log\n"), 0644)
+
+	return dir
+}
+
+func TestGitFiles(t *testing.T) {
+	dir := gitFixture(t)
+	defer os.RemoveAll(dir)
+
+	root, err := GitRoot(filepath.Join(dir, "app.go"))
+
+	if err != nil || !sameFile(root, dir) {
+		flux.FatalFailed(t, "Unexpected git root %q: %s", root, err)
+	}
+
+	files, err := GitFiles(root)
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to list git files: %s", err)
+	}
+
+	sort.Strings(files)
+
+	expected := []string{filepath.Join(root, ".gitignore"), filepath.Join(root, "app.go"), filepath.Join(root, "new.go")}
+
+	if len(files) != len(expected) {
+		flux.FatalFailed(t, "Expected tracked and untracked files without ignored ones: %+s", files)
+	}
+
+	for i := range expected {
+		if files[i] != expected[i] {
+			flux.FatalFailed(t, "Expected %q at %d: %+s", expected[i], i, files)
+		}
+	}
+
+	set, err := newGitFileSet(root)
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to load git file set: %s", err)
+	}
+
+	if !set.has(filepath.Join(dir, "app.go")) || !set.has(dir) || set.has(filepath.Join(dir, "debug.log")) {
+		flux.FatalFailed(t, "Unexpected git file set: %+s", set.files)
+	}
+
+	flux.LogPassed(t, "Successfully listed git files: %+s", files)
+}
+
+func TestGitSymlinkedCheckout(t *testing.T) {
+	dir := gitFixture(t)
+	defer os.RemoveAll(dir)
+
+	link := dir + "-link"
+
+	if err := os.Symlink(dir, link); err != nil {
+		t.Skipf("symlinks are not supported: %s", err)
+	}
+
+	defer os.Remove(link)
+
+	root, err := GitRoot(link)
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to find the git root of the symlink: %s", err)
+	}
+
+	set, err := newGitFileSet(link)
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to load git file set: %s", err)
+	}
+
+	for _, base := range []string{link, dir, root} {
+		if !set.has(filepath.Join(base, "app.go")) || !set.has(base) || set.has(filepath.Join(base, "debug.log")) {
+			flux.FatalFailed(t, "Expected the files to be found through %q: %+s", base, set.files)
+		}
+	}
+
+	if !set.has(filepath.Join(link, "new.go")) {
+		flux.FatalFailed(t, "Expected the untracked file to be found through the symlink")
+	}
+
+	flux.LogPassed(t, "Successfully matched the files of a symlinked checkout")
+}
+
+// sameFile returns true if both paths lead to the same file
+func sameFile(a, b string) bool {
+	ai, err := os.Stat(a)
+
+	if err != nil {
+		return false
+	}
+
+	bi, err := os.Stat(b)
+	return err == nil && os.SameFile(ai, bi)
+}
+
+func TestGitChangedSince(t *testing.T) {
+	dir := gitFixture(t)
+	defer os.RemoveAll(dir)
+
+	ioutil.WriteFile(filepath.Join(dir, "app.go"), []byte("package app\n\nvar changed = true\n"), 0644)
+
+	files, err := GitChangedSince(dir, "HEAD")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to list changed files: %s", err)
+	}
+
+	sort.Strings(files)
+
+	if len(files) != 2 || files[0] != filepath.Join(dir, "app.go") || files[1] != filepath.Join(dir, "new.go") {
+		flux.FatalFailed(t, "Expected the modified and untracked files: %+s", files)
+	}
+
+	if _, err := GitChangedSince(dir, "no-such-ref"); err == nil {
+		flux.FatalFailed(t, "Expected an error for an unknown ref")
+	}
+
+	flux.LogPassed(t, "Successfully listed changed files: %+s", files)
+}
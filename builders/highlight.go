@@ -0,0 +1,205 @@
+package builders
+
+import (
+	"bytes"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"github.com/alecthomas/chroma"
+	chromahtml "github.com/alecthomas/chroma/formatters/html"
+	"github.com/alecthomas/chroma/lexers"
+	"github.com/alecthomas/chroma/styles"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// HighlightConfig provides the configuration for highlighting the fenced code blocks of rendered markdown
+type HighlightConfig struct {
+	Enabled     bool   // Enabled turns on highlighting when the config is part of MarkConfig, MarkStreamConfig or SiteConfig
+	Style       string // Optional: name of the chroma style eg. monokai or dracula, defaults to github
+	Inline      bool   // Optional: if true the colors are set in style attributes instead of classes needing the CSSFile
+	LineNumbers bool   // Optional: if true line numbers are added to the code blocks
+	CSSFile     string // Optional: path the stylesheet of the classes is written to, a relative path is within the SaveDir of MarkFriday, MarkFridayStream and Site
+}
+
+// codeBlock matches the code blocks with a language class rendered by the markdown engines
+var codeBlock = regexp.MustCompile(`(?s)<pre><code class="language-([\w+#.-]+)">(.*?)</code></pre>`)
+
+// codeLanguage matches the language classes of code tags
+var codeLanguage = regexp.MustCompile(`^language-[\w+#.-]+$`)
+
+// markPolicy returns the sanitizing policy of the config if sanitize is true or nil, when highlighting is enabled the
+// policy keeps the language classes of code tags so the sanitized code blocks can still be highlighted
+func markPolicy(sanitize bool, p PolicyConfig, h HighlightConfig) (*bluemonday.Policy, error) {
+	if !sanitize {
+		return nil, nil
+	}
+
+	policy, err := p.Policy()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if h.Enabled {
+		policy.AllowAttrs("class").Matching(codeLanguage).OnElements("code")
+	}
+
+	return policy, nil
+}
+
+// highlighter highlights the code blocks of rendered markdown with chroma
+type highlighter struct {
+	style     *chroma.Style
+	formatter *chromahtml.Formatter
+}
+
+func newHighlighter(config HighlightConfig) (*highlighter, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.Style == "" {
+		config.Style = "github"
+	}
+
+	return &highlighter{
+		style:     styles.Get(config.Style),
+		formatter: chromahtml.New(chromahtml.WithClasses(!config.Inline), chromahtml.WithLineNumbers(config.LineNumbers)),
+	}, nil
+}
+
+// render replaces the code blocks of the html of languages known to chroma with their highlighted version, the blocks
+// of unknown languages are left as they are
+func (h *highlighter) render(data []byte) []byte {
+	return codeBlock.ReplaceAllFunc(data, func(block []byte) []byte {
+		match := codeBlock.FindSubmatch(block)
+		lexer := lexers.Get(string(match[1]))
+
+		if lexer == nil {
+			return block
+		}
+
+		iterator, err := chroma.Coalesce(lexer).Tokenise(nil, html.UnescapeString(string(match[2])))
+
+		if err != nil {
+			return block
+		}
+
+		var buf bytes.Buffer
+
+		if err := h.formatter.Format(&buf, h.style, iterator); err != nil {
+			return block
+		}
+
+		return buf.Bytes()
+	})
+}
+
+// css returns the stylesheet of the highlighting classes
+func (h *highlighter) css() ([]byte, error) {
+	var buf bytes.Buffer
+
+	if err := h.formatter.WriteCSS(&buf, h.style); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// HighlightCSS returns the stylesheet of the highlighting classes of the config style
+func HighlightCSS(config HighlightConfig) ([]byte, error) {
+	h, err := newHighlighter(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return h.css()
+}
+
+// cssState tracks the writes of the stylesheet of a Highlight task, which is written again on every run in case a
+// clean step removed it
+type cssState struct {
+	path    string
+	mu      sync.Mutex
+	written bool
+	last    string
+}
+
+// needs returns true if the stylesheet has to be written for the render of the correlation ID, i.e on the first
+// render of a run or when the file is missing
+func (c *cssState) needs(id string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.written && c.last == id {
+		if _, err := os.Stat(c.path); err == nil {
+			return false
+		}
+	}
+
+	c.written, c.last = true, id
+	return true
+}
+
+// cssPath returns the CSSFile of the highlight config resolved within the save dir if it is relative
+func cssPath(h HighlightConfig, saveDir string) HighlightConfig {
+	if h.CSSFile != "" && saveDir != "" && !filepath.IsAbs(h.CSSFile) {
+		h.CSSFile = filepath.Join(saveDir, h.CSSFile)
+	}
+	return h
+}
+
+// Highlight returns a task which highlights the fenced code blocks within the rendered html of the *RenderFile it
+// receives and replies a *RenderFile of the result. It must come after any sanitizer as the highlighted blocks carry
+// class or style attributes that sanitizing policies strip, the code blocks are found by the language-* class of their
+// code tag which the sanitizing policy must keep as MarkFriday and Site do when highlighting. When the config has a
+// CSSFile and is not Inline the stylesheet is written to it through a fs.FileWriter with the first file of every run,
+// or any file if the stylesheet is missing
+func Highlight(config HighlightConfig) (flux.Reactor, error) {
+	h, err := newHighlighter(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var css flux.Reactor
+
+	if config.CSSFile != "" && !config.Inline {
+		css = fs.FileWriter(nil)
+		css.React(func(_ flux.Reactor, err error, _ interface{}) {
+			if err != nil {
+				logs.Error(nil, "highlight stylesheet write failed", logs.Task("Highlight"), logs.Path(config.CSSFile), logs.Err(err))
+			}
+		}, true)
+	}
+
+	state := &cssState{path: config.CSSFile}
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		rf, ok := data.(*RenderFile)
+
+		if !ok {
+			return
+		}
+
+		if css != nil && state.needs(rf.ID) {
+			sheet, err := h.css()
+
+			if err != nil {
+				root.ReplyError(err)
+				return
+			}
+
+			css.Send(&fs.FileWrite{Path: config.CSSFile, Data: sheet, ID: rf.ID})
+		}
+
+		root.Reply(rf.withData(h.render(rf.Data)))
+	})), nil
+}
@@ -0,0 +1,107 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestHighlighter(t *testing.T) {
+	source := []byte("```go\nfunc main() { fmt.Println(\"<hi>\") }\n```\n\n```nolang\nplain\n```\n")
+
+	for _, engine := range []string{"blackfriday", "gfm"} {
+		policy, err := markPolicy(true, PolicyConfig{}, HighlightConfig{Enabled: true})
+
+		if err != nil {
+			flux.FatalFailed(t, "Unable to create policy: %s", err)
+		}
+
+		render, err := markdownRenderer(MarkdownOptions{Engine: engine}, policy)
+
+		if err != nil {
+			flux.FatalFailed(t, "Unable to create %s renderer: %s", engine, err)
+		}
+
+		h, err := newHighlighter(HighlightConfig{Style: "monokai"})
+
+		if err != nil {
+			flux.FatalFailed(t, "Unable to create highlighter: %s", err)
+		}
+
+		out := string(h.render(render(source)))
+
+		if !strings.Contains(out, `<span class="kd">func</span>`) || !strings.Contains(out, "&lt;hi&gt;") {
+			flux.FatalFailed(t, "Expected the sanitized %s go block to be highlighted with classes: %s", engine, out)
+		}
+
+		if !strings.Contains(out, `<code class="language-nolang">plain`) {
+			flux.FatalFailed(t, "Expected unknown languages to be left as is: %s", out)
+		}
+	}
+
+	h, _ := newHighlighter(HighlightConfig{Inline: true})
+
+	if out := string(h.render([]byte(`<pre><code class="language-go">var x = 1</code></pre>`))); !strings.Contains(out, `style="`) || strings.Contains(out, `class="kd"`) {
+		flux.FatalFailed(t, "Expected inline styles: %s", out)
+	}
+
+	css, err := HighlightCSS(HighlightConfig{Style: "monokai"})
+
+	if err != nil || !strings.Contains(string(css), ".chroma .kd") {
+		flux.FatalFailed(t, "Expected the class stylesheet: %s %s", css, err)
+	}
+
+	if err := (MarkConfig{Highlight: HighlightConfig{Enabled: true, Style: "nope", Inline: true, CSSFile: "x.css"}}).Validate(); err == nil || !err.(*ValidationError).Has("Highlight.Style") || !err.(*ValidationError).Has("Highlight.CSSFile") {
+		flux.FatalFailed(t, "Expected the highlight config to fail validation: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully highlighted code blocks")
+}
+
+func TestHighlightCSSFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-highlight")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	config := cssPath(HighlightConfig{CSSFile: "chroma.css"}, dir)
+
+	if config.CSSFile != filepath.Join(dir, "chroma.css") {
+		flux.FatalFailed(t, "Expected the CSSFile to be within the save dir: %s", config.CSSFile)
+	}
+
+	if abs := cssPath(HighlightConfig{CSSFile: "/css/chroma.css"}, dir); abs.CSSFile != "/css/chroma.css" {
+		flux.FatalFailed(t, "Expected an absolute CSSFile to be kept: %s", abs.CSSFile)
+	}
+
+	state := &cssState{path: config.CSSFile}
+
+	if !state.needs("run-1") {
+		flux.FatalFailed(t, "Expected the first render to write the stylesheet")
+	}
+
+	ioutil.WriteFile(config.CSSFile, []byte("pre {}"), 0644)
+
+	if state.needs("run-1") {
+		flux.FatalFailed(t, "Expected the existing stylesheet to be written once per run")
+	}
+
+	os.Remove(config.CSSFile)
+
+	if !state.needs("run-1") {
+		flux.FatalFailed(t, "Expected a removed stylesheet to be written again")
+	}
+
+	if !state.needs("run-2") {
+		flux.FatalFailed(t, "Expected a new run to write the stylesheet")
+	}
+
+	flux.LogPassed(t, "Successfully tracked the stylesheet writes")
+}
@@ -2,44 +2,198 @@ package builders
 
 import (
 	"bytes"
+	"encoding/base64"
 	"errors"
+	"fmt"
 
 	build "github.com/gopherjs/gopherjs/build"
 	"github.com/gopherjs/gopherjs/compiler"
+	"github.com/gopherjs/gopherjs/compiler/prelude"
 	"github.com/neelance/sourcemap"
 )
 
 // ErrNotMain is returned when we find no .go file with 'main' package
 var ErrNotMain = errors.New("Package contains no 'main' go package file")
 
+// SourceMapMode defines how a JSSession emits the source map of a build
+type SourceMapMode int
+
+const (
+	// SourceMapDefault uses the source map mode of the selected BuildProfile
+	SourceMapDefault SourceMapMode = iota
+	// SourceMapFile writes the map into the js.map buffer and links it from the js output
+	SourceMapFile
+	// SourceMapInline embeds the map into the js output as a base64 data url
+	SourceMapInline
+	// SourceMapNone produces no source map
+	SourceMapNone
+)
+
+// MinifyMode defines if a JSSession minifies its output
+type MinifyMode int
+
+const (
+	// MinifyDefault uses the minify setting of the selected BuildProfile
+	MinifyDefault MinifyMode = iota
+	// MinifyOn forces minified output
+	MinifyOn
+	// MinifyOff forces readable output
+	MinifyOff
+)
+
+// BuildProfile names a preset of minify and source map settings for a JSSession
+type BuildProfile int
+
+const (
+	// DefaultProfile produces minified output with a linked map file
+	DefaultProfile BuildProfile = iota
+	// DebugProfile produces readable output with a linked map file
+	DebugProfile
+	// ReleaseProfile produces minified output without any source map
+	ReleaseProfile
+)
+
+// UnmarshalText sets the mode from one of default, file, inline or none allowing its use in config files
+func (s *SourceMapMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "default":
+		*s = SourceMapDefault
+	case "file":
+		*s = SourceMapFile
+	case "inline":
+		*s = SourceMapInline
+	case "none":
+		*s = SourceMapNone
+	default:
+		return fmt.Errorf("unknown source map mode %q", text)
+	}
+	return nil
+}
+
+// UnmarshalText sets the mode from one of default, on or off allowing its use in config files
+func (m *MinifyMode) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "default":
+		*m = MinifyDefault
+	case "on":
+		*m = MinifyOn
+	case "off":
+		*m = MinifyOff
+	default:
+		return fmt.Errorf("unknown minify mode %q", text)
+	}
+	return nil
+}
+
+// UnmarshalText sets the profile from one of default, debug or release allowing its use in config files
+func (b *BuildProfile) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "", "default":
+		*b = DefaultProfile
+	case "debug":
+		*b = DebugProfile
+	case "release":
+		*b = ReleaseProfile
+	default:
+		return fmt.Errorf("unknown build profile %q", text)
+	}
+	return nil
+}
+
+// JSOptions defines the build options used in creating a JSSession
+type JSOptions struct {
+	Profile   BuildProfile  // Optional: preset for Minify and SourceMap, defaults to DefaultProfile
+	Minify    MinifyMode    // Optional: overrides the profile minify setting
+	SourceMap SourceMapMode // Optional: overrides the profile source map mode
+	GOOS      string        // Optional: added to the build tags to select GOOS specific files eg. linux
+	GOARCH    string        // Optional: added to the build tags to select GOARCH specific files eg. amd64
+	Tags      []string      // Optional: build tags for the build process
+	Verbose   bool
+	Watch     bool
+}
+
+// Minified returns true/false if the options produce minified output
+func (o JSOptions) Minified() bool {
+	switch o.Minify {
+	case MinifyOn:
+		return true
+	case MinifyOff:
+		return false
+	}
+
+	return o.Profile != DebugProfile
+}
+
+// MapMode returns the source map mode to be used by the options, resolving SourceMapDefault through the profile
+func (o JSOptions) MapMode() SourceMapMode {
+	if o.SourceMap != SourceMapDefault {
+		return o.SourceMap
+	}
+
+	if o.Profile == ReleaseProfile {
+		return SourceMapNone
+	}
+
+	return SourceMapFile
+}
+
+// BuildTags returns the combination of the tags with the GOOS and GOARCH values
+func (o JSOptions) BuildTags() []string {
+	var tags []string
+
+	tags = append(tags, o.Tags...)
+
+	if o.GOOS != "" {
+		tags = append(tags, o.GOOS)
+	}
+
+	if o.GOARCH != "" {
+		tags = append(tags, o.GOARCH)
+	}
+
+	return tags
+}
+
 // JSSession represents a basic build.Session with its option
 type JSSession struct {
 	//Dir to use for the virtual files
-	dir     string
-	Session *build.Session
-	Option  *build.Options
+	dir       string
+	SourceMap SourceMapMode
+	Session   *build.Session
+	Option    *build.Options
 }
 
 // NewJSSession returns a new session for build js files
 func NewJSSession(tags []string, verbose, watch bool) *JSSession {
+	return NewJSSessionWith(JSOptions{
+		Tags:    tags,
+		Verbose: verbose,
+		Watch:   watch,
+	})
+}
+
+// NewJSSessionWith returns a new session for build js files using the given JSOptions
+func NewJSSessionWith(o JSOptions) *JSSession {
+	mode := o.MapMode()
 
 	options := &build.Options{
-		Verbose:       verbose,
-		Watch:         watch,
-		CreateMapFile: true,
-		Minify:        true,
-		BuildTags:     tags,
+		Verbose:       o.Verbose,
+		Watch:         o.Watch,
+		CreateMapFile: mode == SourceMapFile,
+		Minify:        o.Minified(),
+		BuildTags:     o.BuildTags(),
 	}
 
 	session := build.NewSession(options)
 
 	return &JSSession{
-		Session: session,
-		Option:  options,
+		SourceMap: mode,
+		Session:   session,
+		Option:    options,
 	}
 }
 
-// BuildPkg uses the session, to build a package file with the given output name and returns two virtual files containing the js and js.map respectively, or an error
+// BuildPkg uses the session, to build a package file with the given output name and returns two virtual files containing the js and js.map respectively, or an error, the js.map is empty unless the session uses SourceMapFile
 func (j *JSSession) BuildPkg(pkg, name string) (*bytes.Buffer, *bytes.Buffer, error) {
 	var js, jsmap *bytes.Buffer = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 
@@ -50,7 +204,7 @@ func (j *JSSession) BuildPkg(pkg, name string) (*bytes.Buffer, *bytes.Buffer, er
 	return js, jsmap, nil
 }
 
-// BuildDir uses the session, to build a particular dir contain files and using the specified package name and output name returns two virtual files containing the js and js.map respectively, or an error
+// BuildDir uses the session, to build a particular dir contain files and using the specified package name and output name returns two virtual files containing the js and js.map respectively, or an error, the js.map is empty unless the session uses SourceMapFile
 func (j *JSSession) BuildDir(dir, importpath, name string) (*bytes.Buffer, *bytes.Buffer, error) {
 	var js, jsmap *bytes.Buffer = bytes.NewBuffer(nil), bytes.NewBuffer(nil)
 
@@ -64,13 +218,36 @@ func (j *JSSession) BuildDir(dir, importpath, name string) (*bytes.Buffer, *byte
 // BuildJSDir builds the js file and returns the content.
 // goPkgPath must be a package path eg. github.com/influx6/haiku-examples/app
 func BuildJSDir(jsession *JSSession, dir, importpath, name string, js, jsmap *bytes.Buffer) error {
+	pkg, err := ImportJSDir(jsession, dir, importpath)
+
+	if err != nil {
+		return err
+	}
+
+	return WriteJS(jsession, pkg.Archive, name, js, jsmap)
+}
+
+// BuildJS builds the js file and returns the content.
+// goPkgPath must be a package path eg. github.com/influx6/haiku-examples/app
+func BuildJS(jsession *JSSession, goPkgPath, name string, js, jsmap *bytes.Buffer) error {
+	pkg, err := ImportJSPkg(jsession, goPkgPath)
+
+	if err != nil {
+		return err
+	}
 
+	return WriteJS(jsession, pkg.Archive, name, js, jsmap)
+}
+
+// ImportJSDir imports the files in the dir under the given import path and compiles them using the session, failed
+// imports and compilations are returned as a *DiagnosticError
+func ImportJSDir(jsession *JSSession, dir, importpath string) (*build.PackageData, error) {
 	session, options := jsession.Session, jsession.Option
 
 	buildpkg, err := build.NewBuildContext(session.InstallSuffix(), options.BuildTags).ImportDir(dir, 0)
 
 	if err != nil {
-		return err
+		return nil, Diagnose(importpath, err)
 	}
 
 	pkg := &build.PackageData{Package: buildpkg}
@@ -78,66 +255,315 @@ func BuildJSDir(jsession *JSSession, dir, importpath, name string, js, jsmap *by
 
 	//build the package using the sessios
 	if err = session.BuildPackage(pkg); err != nil {
+		return nil, Diagnose(importpath, err)
+	}
+
+	return pkg, nil
+}
+
+// ImportJSPkg imports the main package at the given package path and compiles it using the session, failed
+// imports and compilations are returned as a *DiagnosticError
+func ImportJSPkg(jsession *JSSession, goPkgPath string) (*build.PackageData, error) {
+	session, options := jsession.Session, jsession.Option
+
+	//get the build path
+	buildpkg, err := build.Import(goPkgPath, 0, session.InstallSuffix(), options.BuildTags)
+
+	if err != nil {
+		return nil, Diagnose(goPkgPath, err)
+	}
+
+	if buildpkg.Name != "main" {
+		return nil, ErrNotMain
+	}
+
+	//build the package using the sessios
+	if err = session.BuildPackage(buildpkg); err != nil {
+		return nil, Diagnose(goPkgPath, err)
+	}
+
+	return buildpkg, nil
+}
+
+// WriteJS writes out the program code of a built archive and its dependencies into the js buffer, the source map
+// is written according to the session SourceMapMode either into the jsmap buffer, inlined into the js buffer or skipped
+func WriteJS(jsession *JSSession, archive *compiler.Archive, name string, js, jsmap *bytes.Buffer) error {
+	deps, err := compiler.ImportDependencies(archive, jsession.Session.ImportContext.Import)
+
+	if err != nil {
 		return err
 	}
 
-	//build up the source map also
+	return writeMapped(jsession, name, js, jsmap, func(w *compiler.SourceMapFilter) error {
+		return compiler.WriteProgramCode(deps, w)
+	})
+}
+
+// writeMapped calls the writer function with a SourceMapFilter over the js buffer and handles the source map
+// according to the session SourceMapMode
+func writeMapped(jsession *JSSession, name string, js, jsmap *bytes.Buffer, fx func(*compiler.SourceMapFilter) error) error {
+	options := jsession.Option
+
 	smfilter := &compiler.SourceMapFilter{Writer: js}
+
+	if jsession.SourceMap == SourceMapNone {
+		return fx(smfilter)
+	}
+
+	//build up the source map also
 	smsrc := &sourcemap.Map{File: name + ".js"}
 	smfilter.MappingCallback = build.NewMappingCallback(smsrc, options.GOROOT, options.GOPATH)
-	deps, err := compiler.ImportDependencies(pkg.Archive, session.ImportContext.Import)
 
-	if err != nil {
+	if err := fx(smfilter); err != nil {
 		return err
 	}
 
-	err = compiler.WriteProgramCode(deps, smfilter)
+	if jsession.SourceMap == SourceMapInline {
+		var inline bytes.Buffer
+		smsrc.WriteTo(&inline)
+		js.WriteString("//# sourceMappingURL=data:application/json;base64," + base64.StdEncoding.EncodeToString(inline.Bytes()) + "\n")
+		return nil
+	}
 
 	smsrc.WriteTo(jsmap)
-	js.WriteString("//# sourceMappingURL=" + name + ".map.js\n")
+	js.WriteString("//# sourceMappingURL=" + name + ".js.map\n")
 
 	return nil
 }
 
-// BuildJS builds the js file and returns the content.
-// goPkgPath must be a package path eg. github.com/influx6/haiku-examples/app
-func BuildJS(jsession *JSSession, goPkgPath, name string, js, jsmap *bytes.Buffer) error {
+// JSEntry defines a main package built as one entrypoint of a multi-entry build
+type JSEntry struct {
+	Name       string // Name is the output name for the entry js and js.map files eg. admin
+	Package    string // Package is the import path of the main package
+	PackageDir string // Optional: PackageDir is an optional directory to be imported under the Package path
+}
 
-	session, options := jsession.Session, jsession.Option
+// JSChunk represents one output file of a multi-entry build
+type JSChunk struct {
+	Name     string
+	JS       *bytes.Buffer
+	JSMap    *bytes.Buffer
+	Packages []string // import paths of the packages written into this chunk
+}
 
-	//get the build path
-	buildpkg, err := build.Import(goPkgPath, 0, session.InstallSuffix(), options.BuildTags)
+// JSBundle contains the shared runtime chunk and the chunk for each entry of a multi-entry build, in the order of
+// the entries. A page must load the Runtime chunk before any one of the entry chunks
+type JSBundle struct {
+	Runtime *JSChunk
+	Entries []*JSChunk
+}
 
-	if err != nil {
-		return err
+// ErrNoEntries is returned when a multi-entry build is given no entries
+var ErrNoEntries = errors.New("multi-entry build requires at least one JSEntry")
+
+// BuildEntries uses the session to build all the entries, the gopherjs prelude and every package imported by more
+// than one entry is written once into a runtime chunk with the given name, while each entry chunk contains only the
+// packages unique to it and the call to start its main package. Dead code is eliminated per entry over its full
+// dependency set, the runtime chunk keeps the declarations any of the entries needs
+func (j *JSSession) BuildEntries(entries []JSEntry, runtimeName string) (*JSBundle, error) {
+	if len(entries) == 0 {
+		return nil, ErrNoEntries
 	}
 
-	if buildpkg.Name != "main" {
-		return ErrNotMain
+	var deps [][]*compiler.Archive
+	var selections []map[*compiler.Decl]struct{}
+	var mains []string
+	var usage = make(map[string]int)
+
+	for _, entry := range entries {
+		var pkg *build.PackageData
+		var err error
+
+		if entry.PackageDir != "" {
+			pkg, err = ImportJSDir(j, entry.PackageDir, entry.Package)
+		} else {
+			pkg, err = ImportJSPkg(j, entry.Package)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		archives, err := compiler.ImportDependencies(pkg.Archive, j.Session.ImportContext.Import)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, archive := range archives {
+			usage[archive.ImportPath]++
+		}
+
+		deps = append(deps, archives)
+		selections = append(selections, dceSelection(archives))
+		mains = append(mains, pkg.Archive.ImportPath)
 	}
 
-	//build the package data for building
-	// pkg := &build.PackageData{Package: buildpkg}
+	//collect the shared archives in the order they first appear, which keeps them sorted by dependency
+	var shared []*compiler.Archive
+	var added = make(map[string]bool)
+
+	for _, archives := range deps {
+		for _, archive := range archives {
+			if usage[archive.ImportPath] < 2 || added[archive.ImportPath] {
+				continue
+			}
+			added[archive.ImportPath] = true
+			shared = append(shared, archive)
+		}
+	}
 
-	//build the package using the sessios
-	if err = session.BuildPackage(buildpkg); err != nil {
-		return err
+	//the session caches archives by import path, so the shared archives hold the same declarations for every entry
+	var union = make(map[*compiler.Decl]struct{})
+
+	for _, selection := range selections {
+		for decl := range selection {
+			union[decl] = struct{}{}
+		}
 	}
 
-	//build up the source map also
-	smfilter := &compiler.SourceMapFilter{Writer: js}
-	smsrc := &sourcemap.Map{File: name + ".js"}
-	smfilter.MappingCallback = build.NewMappingCallback(smsrc, options.GOROOT, options.GOPATH)
-	deps, err := compiler.ImportDependencies(buildpkg.Archive, session.ImportContext.Import)
+	minify := j.Option.Minify
+
+	runtimeChunk := newJSChunk(runtimeName, shared)
+	err := writeMapped(j, runtimeChunk.Name, runtimeChunk.JS, runtimeChunk.JSMap, func(w *compiler.SourceMapFilter) error {
+		//the runtime is written at the top level so the prelude and $packages are shared with the entry chunks
+		if _, err := w.Write([]byte("\"use strict\";\n" + prelude.Prelude + "\n")); err != nil {
+			return err
+		}
+		return writePkgs(shared, union, minify, w)
+	})
 
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	err = compiler.WriteProgramCode(deps, smfilter)
+	bundle := &JSBundle{Runtime: runtimeChunk}
 
-	smsrc.WriteTo(jsmap)
-	js.WriteString("//# sourceMappingURL=" + name + ".map.js\n")
+	for index, entry := range entries {
+		var own []*compiler.Archive
+
+		for _, archive := range deps[index] {
+			if !added[archive.ImportPath] {
+				own = append(own, archive)
+			}
+		}
+
+		chunk := newJSChunk(entry.Name, own)
+		main := mains[index]
+
+		err := writeMapped(j, chunk.Name, chunk.JS, chunk.JSMap, func(w *compiler.SourceMapFilter) error {
+			if _, err := w.Write([]byte("\"use strict\";\n(function() {\n\n")); err != nil {
+				return err
+			}
+
+			if err := writePkgs(own, selections[index], minify, w); err != nil {
+				return err
+			}
+
+			_, err := w.Write([]byte("$synthesizeMethods();\nvar $mainPkg = $packages[\"" + main + "\"];\n$packages[\"runtime\"].$init();\n$go($mainPkg.$init, [], true);\n$flushConsole();\n\n}).call(this);\n"))
+			return err
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		bundle.Entries = append(bundle.Entries, chunk)
+	}
+
+	return bundle, nil
+}
+
+func newJSChunk(name string, archives []*compiler.Archive) *JSChunk {
+	chunk := &JSChunk{
+		Name:  name,
+		JS:    bytes.NewBuffer(nil),
+		JSMap: bytes.NewBuffer(nil),
+	}
+
+	for _, archive := range archives {
+		chunk.Packages = append(chunk.Packages, archive.ImportPath)
+	}
+
+	return chunk
+}
+
+// writePkgs writes the code of each archive with the declarations of the selection
+func writePkgs(archives []*compiler.Archive, selection map[*compiler.Decl]struct{}, minify bool, w *compiler.SourceMapFilter) error {
+	for _, archive := range archives {
+		if err := compiler.WritePkgCode(archive, selection, minify, w); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
+
+// dceSelection returns the declarations of the archives reachable from their unfiltered ones, the same dead code
+// elimination compiler.WriteProgramCode runs over a single program
+func dceSelection(archives []*compiler.Archive) map[*compiler.Decl]struct{} {
+	type dceInfo struct {
+		decl         *compiler.Decl
+		objectFilter string
+		methodFilter string
+	}
+
+	var byFilter = make(map[string][]*dceInfo)
+	var pending []*compiler.Decl
+
+	for _, archive := range archives {
+		for _, decl := range archive.Declarations {
+			if decl.DceObjectFilter == "" && decl.DceMethodFilter == "" {
+				pending = append(pending, decl)
+				continue
+			}
+
+			info := &dceInfo{decl: decl}
+
+			if decl.DceObjectFilter != "" {
+				info.objectFilter = archive.ImportPath + "." + decl.DceObjectFilter
+				byFilter[info.objectFilter] = append(byFilter[info.objectFilter], info)
+			}
+
+			if decl.DceMethodFilter != "" {
+				info.methodFilter = archive.ImportPath + "." + decl.DceMethodFilter
+				byFilter[info.methodFilter] = append(byFilter[info.methodFilter], info)
+			}
+		}
+	}
+
+	var selection = make(map[*compiler.Decl]struct{})
+
+	for len(pending) != 0 {
+		decl := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+
+		selection[decl] = struct{}{}
+
+		for _, dep := range decl.DceDeps {
+			infos, ok := byFilter[dep]
+
+			if !ok {
+				continue
+			}
+
+			delete(byFilter, dep)
+
+			for _, info := range infos {
+				if info.objectFilter == dep {
+					info.objectFilter = ""
+				}
+
+				if info.methodFilter == dep {
+					info.methodFilter = ""
+				}
+
+				if info.objectFilter == "" && info.methodFilter == "" {
+					pending = append(pending, info.decl)
+				}
+			}
+		}
+	}
+
+	return selection
+}
@@ -3,6 +3,7 @@ package builders
 import (
 	"testing"
 
+	"github.com/gopherjs/gopherjs/compiler"
 	"github.com/influx6/flux"
 )
 
@@ -41,3 +42,98 @@ func TestJSDirBundler(t *testing.T) {
 		flux.LogPassed(t, "Successfully built js package: %d", js.Len())
 	}
 }
+
+func TestJSOptionsProfiles(t *testing.T) {
+	if !(JSOptions{}).Minified() || (JSOptions{}).MapMode() != SourceMapFile {
+		flux.FatalFailed(t, "Default profile should be minified with a map file")
+	}
+
+	if (JSOptions{Profile: DebugProfile}).Minified() {
+		flux.FatalFailed(t, "Debug profile should not be minified")
+	}
+
+	if (JSOptions{Profile: ReleaseProfile}).MapMode() != SourceMapNone {
+		flux.FatalFailed(t, "Release profile should produce no source map")
+	}
+
+	if !(JSOptions{Profile: DebugProfile, Minify: MinifyOn}).Minified() {
+		flux.FatalFailed(t, "MinifyOn should override the debug profile")
+	}
+
+	tags := (JSOptions{Tags: []string{"dev"}, GOOS: "linux", GOARCH: "amd64"}).BuildTags()
+	if len(tags) != 3 || tags[1] != "linux" || tags[2] != "amd64" {
+		flux.FatalFailed(t, "Expected GOOS and GOARCH in build tags: %s", tags)
+	}
+
+	flux.LogPassed(t, "Successfully resolved JSOptions profiles")
+}
+
+func TestJSDceSelection(t *testing.T) {
+	used := &compiler.Decl{DceObjectFilter: "Used"}
+	unused := &compiler.Decl{DceObjectFilter: "Unused"}
+	main := &compiler.Decl{DceDeps: []string{"lib.Used"}}
+
+	selection := dceSelection([]*compiler.Archive{
+		{ImportPath: "lib", Declarations: []*compiler.Decl{used, unused}},
+		{ImportPath: "app", Declarations: []*compiler.Decl{main}},
+	})
+
+	if _, ok := selection[main]; !ok {
+		flux.FatalFailed(t, "Expected the unfiltered declaration to be selected")
+	}
+
+	if _, ok := selection[used]; !ok {
+		flux.FatalFailed(t, "Expected the used declaration to be selected")
+	}
+
+	if _, ok := selection[unused]; ok {
+		flux.FatalFailed(t, "Expected the unused declaration to be eliminated")
+	}
+
+	flux.LogPassed(t, "Successfully eliminated the unused declarations")
+}
+
+func TestJSReleaseBundler(t *testing.T) {
+	release := NewJSSessionWith(JSOptions{Profile: ReleaseProfile})
+
+	js, jsmap, err := release.BuildPkg("github.com/influx6/reactors/builders/base", "base")
+
+	if err != nil {
+		flux.FatalFailed(t, "Error build gopherjs package: %s", err)
+	}
+
+	if jsmap.Len() != 0 {
+		flux.FatalFailed(t, "Expected no js.map for release build: %d", jsmap.Len())
+	}
+
+	if js.Len() > 50 {
+		flux.LogPassed(t, "Successfully built release js package: %d", js.Len())
+	}
+}
+
+func TestJSEntriesBundler(t *testing.T) {
+	bundle, err := session.BuildEntries([]JSEntry{
+		{Name: "base", Package: "github.com/influx6/reactors/builders/base"},
+		{Name: "worker", Package: "github.com/influx6/reactors/builders/worker"},
+	}, "runtime")
+
+	if err != nil {
+		flux.FatalFailed(t, "Error building gopherjs entries: %s", err)
+	}
+
+	if len(bundle.Entries) != 2 {
+		flux.FatalFailed(t, "Expected two entry chunks: %d", len(bundle.Entries))
+	}
+
+	for _, chunk := range bundle.Entries {
+		for _, pkg := range chunk.Packages {
+			for _, shared := range bundle.Runtime.Packages {
+				if pkg == shared {
+					flux.FatalFailed(t, "Package %s found in both runtime and %s chunk", pkg, chunk.Name)
+				}
+			}
+		}
+	}
+
+	flux.LogPassed(t, "Successfully built runtime chunk with %d packages", len(bundle.Runtime.Packages))
+}
@@ -0,0 +1,245 @@
+package builders
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/logs"
+)
+
+// ErrLayoutNotFound is returned when a file asks for a layout which is not in the template directory
+var ErrLayoutNotFound = errors.New("Layout template not found")
+
+// LayoutConfig provides the configuration for the Layout task
+type LayoutConfig struct {
+	TemplateDir string           // TemplateDir holds the layouts and partials, each file is named by its path relative to the dir without its extension eg. default or partials/header
+	Layout      string           // Optional: layout used by the files without a layout key in their front matter, defaults to default
+	Exts        []string         // Optional: extensions of the template files, defaults to .html and .tmpl
+	Funcs       template.FuncMap `json:"-"` // Optional: functions made available to the templates
+	Logger      logs.Logger      // Optional: Logger receives the template reloads, defaults to the global logs logger
+}
+
+// Page is the data a layout is executed with
+type Page struct {
+	Path      string                 // Path of the source file
+	Content   template.HTML          // Content is the rendered output of the file
+	Meta      map[string]interface{} // Meta is the front matter of the file
+	Title     string                 // Title is the title key of the front matter if any
+	Date      time.Time              // Date is the date key of the front matter if any
+	Permalink string                 // Permalink is the url of the page when built by Site
+	TOC       []*Heading             // TOC holds the headings of the page when generated
+}
+
+// newPage returns the Page of the file
+func newPage(rf *RenderFile) Page {
+	return Page{
+		Path:    rf.Path,
+		Content: template.HTML(rf.Data),
+		Meta:    rf.Meta,
+		Title:   MetaString(rf.Meta, "title"),
+		Date:    MetaTime(rf.Meta, "date"),
+		TOC:     rf.TOC,
+
+		Permalink: rf.Permalink,
+	}
+}
+
+// layoutRescan is how long the templates are used for files without a correlation ID before the dir is scanned again
+const layoutRescan = time.Second
+
+// layoutSet keeps the parsed templates of a directory and parses them again when any of the files change, the dir is
+// scanned once per run of a correlation ID
+type layoutSet struct {
+	config    LayoutConfig
+	mu        sync.Mutex
+	signature string
+	tmpl      *template.Template
+	run       string
+	scanned   time.Time
+}
+
+// newLayoutSet returns a layoutSet of the config with its defaults set and its templates parsed
+func newLayoutSet(config LayoutConfig) (*layoutSet, error) {
+	if config.Layout == "" {
+		config.Layout = "default"
+	}
+
+	if len(config.Exts) == 0 {
+		config.Exts = []string{".html", ".tmpl"}
+	}
+
+	set := &layoutSet{config: config}
+
+	if _, err := set.templates(""); err != nil {
+		return nil, err
+	}
+
+	return set, nil
+}
+
+// scan returns the template files of the directory and a signature of their names, sizes and modification times
+func (l *layoutSet) scan() ([]string, string, error) {
+	var files []string
+	var sig []string
+
+	err := filepath.Walk(l.config.TemplateDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !l.hasExt(path) {
+			return nil
+		}
+
+		files = append(files, path)
+		sig = append(sig, fmt.Sprintf("%s:%d:%d", path, info.Size(), info.ModTime().UnixNano()))
+		return nil
+	})
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	sort.Strings(sig)
+	return files, strings.Join(sig, "|"), nil
+}
+
+func (l *layoutSet) hasExt(path string) bool {
+	ext := filepath.Ext(path)
+
+	for _, allowed := range l.config.Exts {
+		if ext == allowed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// templates returns the parsed templates for the run of the correlation ID, scanning the dir on the first file of a
+// run, or once layoutRescan passed for files without an ID, and parsing it again if the files changed since
+func (l *layoutSet) templates(id string) (*template.Template, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.tmpl != nil && id == l.run && (id != "" || time.Since(l.scanned) < layoutRescan) {
+		return l.tmpl, nil
+	}
+
+	files, sig, err := l.scan()
+
+	if err != nil {
+		return nil, err
+	}
+
+	l.run, l.scanned = id, time.Now()
+
+	if l.tmpl != nil && sig == l.signature {
+		return l.tmpl, nil
+	}
+
+	tmpl := template.New("").Funcs(l.config.Funcs)
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rel, _ := filepath.Rel(l.config.TemplateDir, file)
+		name := filepath.ToSlash(strings.TrimSuffix(rel, filepath.Ext(rel)))
+
+		if _, err := tmpl.New(name).Parse(string(data)); err != nil {
+			return nil, err
+		}
+	}
+
+	if l.tmpl != nil {
+		logs.Debug(l.config.Logger, "layouts reloaded", logs.Task("Layout"), logs.Path(l.config.TemplateDir))
+	}
+
+	l.tmpl, l.signature = tmpl, sig
+	return tmpl, nil
+}
+
+// execute executes the named template of the set for the run of the correlation ID with the data
+func (l *layoutSet) execute(id, name string, data interface{}) ([]byte, error) {
+	tmpl, err := l.templates(id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tmpl.Lookup(name) == nil {
+		return nil, fmt.Errorf("%w: %q", ErrLayoutNotFound, name)
+	}
+
+	var buf bytes.Buffer
+
+	if err := tmpl.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// has returns true if the set has a template of the name for the run of the correlation ID
+func (l *layoutSet) has(id, name string) bool {
+	tmpl, err := l.templates(id)
+	return err == nil && tmpl.Lookup(name) != nil
+}
+
+// render executes the layout of the file with its content
+func (l *layoutSet) render(rf *RenderFile) ([]byte, error) {
+	name := MetaString(rf.Meta, "layout")
+
+	if name == "" {
+		name = l.config.Layout
+	}
+
+	return l.execute(rf.ID, name, newPage(rf))
+}
+
+// Layout returns a task which executes a layout template from the config TemplateDir with each *RenderFile it
+// receives as a Page and replies a *RenderFile of the full page. The layout is chosen by the layout key of the file
+// front matter or the config Layout, layouts can include the other templates of the dir as partials by their names.
+// The dir is checked for changes once per run of a correlation ID and the templates are parsed again if any changed
+func Layout(config LayoutConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	set, err := newLayoutSet(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		rf, ok := data.(*RenderFile)
+
+		if !ok {
+			return
+		}
+
+		page, err := set.render(rf)
+
+		if err != nil {
+			root.ReplyError(fmt.Errorf("%s: %w", rf.Path, err))
+			return
+		}
+
+		root.Reply(rf.withData(page))
+	})), nil
+}
@@ -0,0 +1,74 @@
+package builders
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestLayoutSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-layouts")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	os.MkdirAll(filepath.Join(dir, "partials"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "default.html"), []byte(`<title>{{.Title}}</title>{{template "partials/nav" .}}<main>{{.Content}}</main>`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "post.html"), []byte(`<article>{{.Content}}</article>`), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "partials", "nav.html"), []byte(`<nav>{{.Path}}</nav>`), 0644)
+
+	set := &layoutSet{config: LayoutConfig{TemplateDir: dir, Layout: "default", Exts: []string{".html"}}}
+
+	page, err := set.render(&RenderFile{Path: "intro.md", Data: []byte("<p>hi</p>"), Meta: map[string]interface{}{"title": "Intro"}})
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to render the default layout: %s", err)
+	}
+
+	if string(page) != `<title>Intro</title><nav>intro.md</nav><main><p>hi</p></main>` {
+		flux.FatalFailed(t, "Unexpected default layout page: %s", page)
+	}
+
+	page, err = set.render(&RenderFile{Path: "post.md", Data: []byte("<p>post</p>"), Meta: map[string]interface{}{"layout": "post"}})
+
+	if err != nil || string(page) != `<article><p>post</p></article>` {
+		flux.FatalFailed(t, "Expected the front matter layout to be used: %s %s", page, err)
+	}
+
+	if _, err = set.render(&RenderFile{Path: "x.md", Meta: map[string]interface{}{"layout": "missing"}}); !errors.Is(err, ErrLayoutNotFound) {
+		flux.FatalFailed(t, "Expected ErrLayoutNotFound: %s", err)
+	}
+
+	page, err = set.render(&RenderFile{Path: "post.md", ID: "run1", Data: []byte("<p>post</p>"), Meta: map[string]interface{}{"layout": "post"}})
+
+	if err != nil || string(page) != `<article><p>post</p></article>` {
+		flux.FatalFailed(t, "Expected the layout of the run to render: %s %s", page, err)
+	}
+
+	post := filepath.Join(dir, "post.html")
+	ioutil.WriteFile(post, []byte(`<section>{{.Content}}</section>`), 0644)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(post, later, later)
+
+	page, err = set.render(&RenderFile{Path: "post.md", ID: "run1", Data: []byte("<p>post</p>"), Meta: map[string]interface{}{"layout": "post"}})
+
+	if err != nil || string(page) != `<article><p>post</p></article>` {
+		flux.FatalFailed(t, "Expected the dir to be scanned once per run: %s %s", page, err)
+	}
+
+	page, err = set.render(&RenderFile{Path: "post.md", ID: "run2", Data: []byte("<p>post</p>"), Meta: map[string]interface{}{"layout": "post"}})
+
+	if err != nil || string(page) != `<section><p>post</p></section>` {
+		flux.FatalFailed(t, "Expected the changed layout to be reloaded: %s %s", page, err)
+	}
+
+	flux.LogPassed(t, "Successfully rendered layouts")
+}
@@ -0,0 +1,311 @@
+package builders
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+// LinkConfig provides the configuration for rewriting and checking the links between markdown files
+type LinkConfig struct {
+	Rewrite bool // Optional: if true relative links to markdown files are rewritten to the output path of the file
+	Check   bool // Optional: if true MarkFridayStream replies a *LinkReport of the broken links once a run is rendered
+	Fail    bool // Optional: if true MarkFridayStream also replies a *BrokenLinksError when a run has broken links
+}
+
+// BrokenLink describes a link to a missing page or anchor
+type BrokenLink struct {
+	Source string // Source is the markdown file containing the link
+	Href   string // Href is the link as written in the file
+	Reason string // Reason tells what is missing
+}
+
+// LinkReport lists the broken links found in a MarkFridayStream run
+type LinkReport struct {
+	ID     string       // ID is the correlation ID of the run
+	Files  int          // Files is the number of files checked
+	Links  int          // Links is the number of links to markdown files and anchors checked
+	Broken []BrokenLink // Broken lists the broken links sorted by source
+}
+
+// Correlation returns the correlation ID of the run
+func (r *LinkReport) Correlation() string {
+	return r.ID
+}
+
+// BrokenLinksError is replied when a run has broken links and the LinkConfig has Fail set
+type BrokenLinksError struct {
+	Report *LinkReport
+}
+
+// Error returns the broken links as a single message
+func (b *BrokenLinksError) Error() string {
+	var msgs []string
+
+	for _, link := range b.Report.Broken {
+		msgs = append(msgs, fmt.Sprintf("%s: %s %s", link.Source, link.Href, link.Reason))
+	}
+
+	return fmt.Sprintf("%d broken links: %s", len(b.Report.Broken), strings.Join(msgs, "; "))
+}
+
+// anchorHref matches the href attributes of anchor tags
+var anchorHref = regexp.MustCompile(`(<a\s[^>]*?href=")([^"]*)(")`)
+
+// isMarkdown returns true if the path has a markdown extension
+func isMarkdown(path string) bool {
+	ext := filepath.Ext(path)
+	return ext == ".md" || ext == ".markdown"
+}
+
+// fileLink is a link from a rendered file to a markdown file or an anchor
+type fileLink struct {
+	href     string
+	target   string
+	fragment string
+}
+
+// resolveLink returns the markdown file and fragment a link of the source file points to, ok is false for links to
+// other sites, absolute paths and files which are not markdown
+func resolveLink(source, href string) (target, fragment string, ok bool) {
+	if href == "" || strings.HasPrefix(href, "/") {
+		return "", "", false
+	}
+
+	parsed, err := url.Parse(href)
+
+	if err != nil || parsed.Scheme != "" || parsed.Host != "" {
+		return "", "", false
+	}
+
+	if parsed.Path == "" {
+		return source, parsed.Fragment, parsed.Fragment != ""
+	}
+
+	if !isMarkdown(parsed.Path) {
+		return "", "", false
+	}
+
+	return filepath.Join(filepath.Dir(source), filepath.FromSlash(parsed.Path)), parsed.Fragment, true
+}
+
+// linkRewriter rewrites links to markdown files into links to their output files
+type linkRewriter struct {
+	config MarkConfig
+	mu     sync.Mutex
+	metas  map[string]cachedMeta
+}
+
+type cachedMeta struct {
+	mod  time.Time
+	meta map[string]interface{}
+}
+
+func newLinkRewriter(config MarkConfig) *linkRewriter {
+	return &linkRewriter{config: config, metas: make(map[string]cachedMeta)}
+}
+
+// meta returns the front matter of the file, cached until the file changes
+func (l *linkRewriter) meta(path string) map[string]interface{} {
+	stat, err := os.Stat(path)
+
+	if err != nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if cached, ok := l.metas[path]; ok && cached.mod.Equal(stat.ModTime()) {
+		return cached.meta
+	}
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil
+	}
+
+	meta, _ := ParseFrontMatter(data)
+	l.metas[path] = cachedMeta{mod: stat.ModTime(), meta: meta}
+	return meta
+}
+
+// rewrite returns the html of the render with its links to markdown files pointing to their output files and the
+// links it found
+func (l *linkRewriter) rewrite(rf *RenderFile, rewrite bool) ([]byte, []fileLink) {
+	var links []fileLink
+	var from string
+
+	out := anchorHref.ReplaceAllFunc(rf.Data, func(attr []byte) []byte {
+		match := anchorHref.FindSubmatch(attr)
+		href := string(match[2])
+		target, fragment, ok := resolveLink(rf.Path, href)
+
+		if !ok {
+			return attr
+		}
+
+		links = append(links, fileLink{href: href, target: target, fragment: fragment})
+
+		if !rewrite || target == rf.Path {
+			return attr
+		}
+
+		if from == "" {
+			from = filepath.Dir(markPath(l.config, rf.Path, rf.Meta))
+		}
+
+		rel, err := filepath.Rel(from, markPath(l.config, target, l.meta(target)))
+
+		if err != nil {
+			return attr
+		}
+
+		link := filepath.ToSlash(rel)
+
+		if fragment != "" {
+			link += "#" + fragment
+		}
+
+		return []byte(string(match[1]) + link + string(match[3]))
+	})
+
+	return out, links
+}
+
+// linkRun collects the links and ids of the files rendered in a run
+type linkRun struct {
+	id    string
+	pages map[string]bool
+	ids   map[string]map[string]bool
+	links map[string][]fileLink
+}
+
+// linkChecker tracks the runs of a MarkFridayStream and sends the report of a run to the reporter once all of its
+// pages were rendered, a new run drops the one in progress
+type linkChecker struct {
+	config   LinkConfig
+	reporter flux.Reactor
+	mu       sync.Mutex
+	run      *linkRun
+}
+
+// start begins tracking the run of the id which renders the given pages
+func (c *linkChecker) start(id string, pages map[string]bool) {
+	run := &linkRun{id: id, pages: pages, ids: make(map[string]map[string]bool), links: make(map[string][]fileLink)}
+
+	c.mu.Lock()
+	c.run = run
+	c.mu.Unlock()
+
+	if len(pages) == 0 {
+		c.send(run)
+	}
+}
+
+// record adds the links and ids of a rendered file to its run, sending the report when it is the last file
+func (c *linkChecker) record(rf *RenderFile, links []fileLink) {
+	var ids = make(map[string]bool)
+
+	for _, match := range headingID.FindAllSubmatch(rf.Data, -1) {
+		ids[string(match[1])] = true
+	}
+
+	path, _ := filepath.Abs(rf.Path)
+
+	c.mu.Lock()
+	run := c.run
+
+	if run == nil || run.id != rf.ID {
+		c.mu.Unlock()
+		return
+	}
+
+	run.ids[path] = ids
+	run.links[path] = links
+	done := len(run.ids) == len(run.pages)
+
+	if done {
+		c.run = nil
+	}
+
+	c.mu.Unlock()
+
+	if done {
+		c.send(run)
+	}
+}
+
+// send checks the links of the run and sends its report
+func (c *linkChecker) send(run *linkRun) {
+	report := run.report()
+
+	c.reporter.Send(report)
+
+	if c.config.Fail && len(report.Broken) > 0 {
+		c.reporter.SendError(&BrokenLinksError{Report: report})
+	}
+}
+
+// report checks the links of the run against the rendered pages and their ids
+func (run *linkRun) report() *LinkReport {
+	report := &LinkReport{ID: run.id, Files: len(run.ids)}
+
+	for source, links := range run.links {
+		for _, link := range links {
+			report.Links++
+
+			target, _ := filepath.Abs(link.target)
+			ids, rendered := run.ids[target]
+
+			switch {
+			case !rendered:
+				report.Broken = append(report.Broken, BrokenLink{Source: source, Href: link.href, Reason: "links to a missing page"})
+			case link.fragment != "" && !ids[link.fragment]:
+				report.Broken = append(report.Broken, BrokenLink{Source: source, Href: link.href, Reason: "links to a missing anchor"})
+			}
+		}
+	}
+
+	sort.Slice(report.Broken, func(i, j int) bool {
+		if report.Broken[i].Source != report.Broken[j].Source {
+			return report.Broken[i].Source < report.Broken[j].Source
+		}
+		return report.Broken[i].Href < report.Broken[j].Href
+	})
+
+	return report
+}
+
+// linkStage returns the MarkFriday stage rewriting the links of the *RenderFile it receives and recording them with
+// the checker if any
+func linkStage(config MarkConfig, checker *linkChecker) flux.Reactor {
+	rewriter := newLinkRewriter(config)
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		rf, ok := data.(*RenderFile)
+
+		if !ok {
+			return
+		}
+
+		out, links := rewriter.rewrite(rf, config.Links.Rewrite)
+		next := rf.withData(out)
+
+		if checker != nil {
+			checker.record(next, links)
+		}
+
+		root.Reply(next)
+	}))
+}
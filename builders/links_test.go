@@ -0,0 +1,117 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestResolveLink(t *testing.T) {
+	source := filepath.Join("docs", "guide", "intro.md")
+
+	cases := []struct {
+		href, target, fragment string
+		ok                     bool
+	}{
+		{"setup.md", filepath.Join("docs", "guide", "setup.md"), "", true},
+		{"../api.md#errors", filepath.Join("docs", "api.md"), "errors", true},
+		{"#usage", source, "usage", true},
+		{"https://example.com/x.md", "", "", false},
+		{"/abs/x.md", "", "", false},
+		{"image.png", "", "", false},
+		{"mailto:dev@example.com", "", "", false},
+	}
+
+	for _, c := range cases {
+		target, fragment, ok := resolveLink(source, c.href)
+
+		if ok != c.ok || target != c.target || fragment != c.fragment {
+			flux.FatalFailed(t, "Unexpected resolution of %q: %q %q %t", c.href, target, fragment, ok)
+		}
+	}
+
+	flux.LogPassed(t, "Successfully resolved links")
+}
+
+func TestLinkCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-links")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	files := map[string]string{
+		"intro.md":       "[setup](guide/setup.md#install) [usage](guide/setup.md#usage) [draft](draft.md) [gone](gone.md) [top](#intro)\n",
+		"guide/setup.md": "---\nslug: getting-started\n---\n## Install\n",
+		"draft.md":       "---\ndraft: true\n---\ndraft\n",
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(src, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(src, name), []byte(content), 0644)
+	}
+
+	config := MarkConfig{SaveDir: filepath.Join(dir, "out"), Ext: ".html"}
+	render, _ := MarkdownOptions{}.Renderer()
+	rewriter := newLinkRewriter(config)
+
+	pages, err := newSourceCache().sources(src, nil, false)
+
+	if err != nil || len(pages) != 2 {
+		flux.FatalFailed(t, "Expected the draft to not be a page: %+v %s", pages, err)
+	}
+
+	run := &linkRun{id: "run", pages: pages, ids: make(map[string]map[string]bool), links: make(map[string][]fileLink)}
+
+	for _, name := range []string{"intro.md", "guide/setup.md"} {
+		path := filepath.Join(src, name)
+		data, _ := ioutil.ReadFile(path)
+		meta, body := ParseFrontMatter(data)
+		html, _ := TOCConfig{}.apply(render(body))
+
+		out, links := rewriter.rewrite(&RenderFile{Path: path, Data: html, Meta: meta, ID: "run"}, true)
+
+		if name == "intro.md" && !strings.Contains(string(out), `href="getting-started.html#install"`) {
+			flux.FatalFailed(t, "Expected the link to point to the output file: %s", out)
+		}
+
+		run.ids[path] = make(map[string]bool)
+
+		for _, match := range headingID.FindAllSubmatch(out, -1) {
+			run.ids[path][string(match[1])] = true
+		}
+
+		run.links[path] = links
+	}
+
+	report := run.report()
+
+	if report.Files != 2 || report.Links != 5 || len(report.Broken) != 4 {
+		flux.FatalFailed(t, "Unexpected report: %+v", report)
+	}
+
+	var reasons []string
+
+	for _, broken := range report.Broken {
+		reasons = append(reasons, broken.Href+" "+broken.Reason)
+	}
+
+	expected := "#intro links to a missing anchor|draft.md links to a missing page|gone.md links to a missing page|guide/setup.md#usage links to a missing anchor"
+
+	if strings.Join(reasons, "|") != expected {
+		flux.FatalFailed(t, "Unexpected broken links: %s", strings.Join(reasons, "|"))
+	}
+
+	if !strings.Contains((&BrokenLinksError{Report: report}).Error(), "4 broken links") {
+		flux.FatalFailed(t, "Expected the error to count the broken links")
+	}
+
+	flux.LogPassed(t, "Successfully checked links: %+v", report)
+}
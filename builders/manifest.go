@@ -0,0 +1,96 @@
+package builders
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+)
+
+// AssetEntry describes a fingerprinted output file recorded in an AssetManifest
+type AssetEntry struct {
+	File      string `json:"file"`
+	Size      int    `json:"size"`
+	Integrity string `json:"integrity"`
+}
+
+// AssetManifest maps the logical name of an output file eg. app.js to its fingerprinted AssetEntry
+type AssetManifest map[string]AssetEntry
+
+// ReadAssetManifest loads the AssetManifest stored at the given path, returning an empty manifest if the file does not exist
+func ReadAssetManifest(path string) (AssetManifest, error) {
+	manifest := make(AssetManifest)
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+// Add records the fingerprinted file for the logical name using the data for its size and integrity hash
+func (a AssetManifest) Add(name, file string, data []byte) {
+	a[name] = AssetEntry{
+		File:      file,
+		Size:      len(data),
+		Integrity: Integrity(data),
+	}
+}
+
+// Replace records the file for the logical name as Add does and returns the file it replaces, or an empty string if
+// the name had no entry or the same file
+func (a AssetManifest) Replace(name, file string, data []byte) string {
+	previous, ok := a[name]
+	a.Add(name, file, data)
+
+	if !ok || previous.File == file {
+		return ""
+	}
+
+	return previous.File
+}
+
+// Path returns the fingerprinted file for the logical name or the name itself if it has no entry, allowing its use as a template function
+func (a AssetManifest) Path(name string) string {
+	if entry, ok := a[name]; ok {
+		return entry.File
+	}
+	return name
+}
+
+// Fingerprint returns the first 8 hex characters of the sha256 hash of the data
+func Fingerprint(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// Integrity returns the subresource integrity value of the data using sha384 eg. sha384-oqVuAfXRKap7fdgcCY5uykM6+R9GqQ8K/uxy9rx7HNQlGYl1kPzQho1wx4JwY8wC
+func Integrity(data []byte) string {
+	sum := sha512.Sum384(data)
+	return "sha384-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// fingerprintJS returns the fingerprinted base name for the js output of the given name eg. app.3f9c2a10, along with
+// the js data whose sourceMappingURL is rewritten to the fingerprinted js.map file
+func fingerprintJS(name string, js []byte) (string, []byte) {
+	base := fmt.Sprintf("%s.%s", name, Fingerprint(js))
+
+	url := []byte("//# sourceMappingURL=" + name + ".js.map")
+	hashed := []byte("//# sourceMappingURL=" + base + ".js.map")
+
+	return base, bytes.Replace(js, url, hashed, 1)
+}
@@ -0,0 +1,60 @@
+package builders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestFingerprintJS(t *testing.T) {
+	js := []byte("console.log(1);\n//# sourceMappingURL=app.js.map\n")
+
+	base, data := fingerprintJS("app", js)
+
+	if base != "app."+Fingerprint(js) {
+		flux.FatalFailed(t, "Unexpected fingerprinted name: %s", base)
+	}
+
+	if !strings.Contains(string(data), "//# sourceMappingURL="+base+".js.map") {
+		flux.FatalFailed(t, "Expected rewritten sourceMappingURL: %s", data)
+	}
+
+	flux.LogPassed(t, "Successfully fingerprinted js as %s", base)
+}
+
+func TestAssetManifest(t *testing.T) {
+	manifest, err := ReadAssetManifest("../fixtures/assets.none.json")
+
+	if err != nil {
+		flux.FatalFailed(t, "Expected empty manifest for missing file: %s", err)
+	}
+
+	manifest.Add("app.js", "app.3f9c2a10.js", []byte("console.log(1);"))
+
+	if manifest.Path("app.js") != "app.3f9c2a10.js" {
+		flux.FatalFailed(t, "Unexpected manifest path: %s", manifest.Path("app.js"))
+	}
+
+	if manifest.Path("site.css") != "site.css" {
+		flux.FatalFailed(t, "Expected unknown names to be returned as is")
+	}
+
+	if !strings.HasPrefix(manifest["app.js"].Integrity, "sha384-") {
+		flux.FatalFailed(t, "Unexpected integrity value: %s", manifest["app.js"].Integrity)
+	}
+
+	if old := manifest.Replace("app.js", "app.3f9c2a10.js", []byte("console.log(1);")); old != "" {
+		flux.FatalFailed(t, "Expected the same file to not be replaced: %s", old)
+	}
+
+	if old := manifest.Replace("app.js", "app.5b1e7d42.js", []byte("console.log(2);")); old != "app.3f9c2a10.js" {
+		flux.FatalFailed(t, "Expected the previous file to be replaced: %s", old)
+	}
+
+	if old := manifest.Replace("vendor.js", "vendor.9a0c4f11.js", []byte("")); old != "" || manifest.Path("vendor.js") != "vendor.9a0c4f11.js" {
+		flux.FatalFailed(t, "Expected a new name to replace nothing: %s", old)
+	}
+
+	flux.LogPassed(t, "Successfully built asset manifest: %+s", manifest)
+}
@@ -0,0 +1,278 @@
+package builders
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/logs"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/russross/blackfriday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// Markdown extension names understood by the builtin engines, not every engine supports every extension
+const (
+	ExtTables          = "tables"
+	ExtFencedCode      = "fenced-code"
+	ExtAutolinks       = "autolinks"
+	ExtStrikethrough   = "strikethrough"
+	ExtTaskLists       = "task-lists"
+	ExtFootnotes       = "footnotes"
+	ExtDefinitionLists = "definition-lists"
+	ExtHeadingIDs      = "heading-ids"
+	ExtTypographer     = "typographer"
+)
+
+// MarkdownOptions selects the markdown engine used to render markdown and configures it
+type MarkdownOptions struct {
+	Engine     string   // Optional: name of a registered engine, blackfriday (default), commonmark or gfm
+	Extensions []string // Optional: extensions enabled instead of the engine defaults eg. tables, footnotes or heading-ids
+	HardWraps  bool     // Optional: if true newlines within paragraphs are rendered as <br> tags
+	XHTML      bool     // Optional: if true void tags are rendered as XHTML eg. <br />
+	Unsafe     bool     // Optional: if true the commonmark and gfm engines render raw html instead of omitting it
+
+	Logger logs.Logger `json:"-"` // Optional: Logger receives the render errors of the engines, defaults to the global logs logger
+}
+
+// MarkdownEngine creates the RenderMux of an engine for the options
+type MarkdownEngine func(MarkdownOptions) (RenderMux, error)
+
+// ErrEngineExists is returned when registering a markdown engine with a name already in use
+var ErrEngineExists = errors.New("markdown engine with the given name is already registered")
+
+// ErrUnknownEngine is returned when the options select an engine which is not registered
+var ErrUnknownEngine = errors.New("markdown engine is not registered")
+
+// ErrUnknownExtension is returned when the options enable an extension the engine does not support
+var ErrUnknownExtension = errors.New("markdown extension is not supported by the engine")
+
+var engines = struct {
+	rw      sync.RWMutex
+	engines map[string]MarkdownEngine
+}{engines: map[string]MarkdownEngine{
+	"blackfriday": blackfridayEngine,
+	"commonmark":  goldmarkEngine(nil),
+	"gfm":         goldmarkEngine([]string{ExtTables, ExtStrikethrough, ExtAutolinks, ExtTaskLists}),
+}}
+
+// RegisterMarkdownEngine adds the engine under the name, returning ErrEngineExists if the name is taken
+func RegisterMarkdownEngine(name string, engine MarkdownEngine) error {
+	engines.rw.Lock()
+	defer engines.rw.Unlock()
+
+	if _, ok := engines.engines[name]; ok {
+		return ErrEngineExists
+	}
+
+	engines.engines[name] = engine
+	return nil
+}
+
+// MarkdownEngines returns the names of the registered engines sorted
+func MarkdownEngines() []string {
+	engines.rw.RLock()
+	defer engines.rw.RUnlock()
+
+	var names []string
+
+	for name := range engines.engines {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// engine returns the name of the selected engine
+func (m MarkdownOptions) engine() string {
+	if m.Engine == "" {
+		return "blackfriday"
+	}
+	return m.Engine
+}
+
+// hasEngine returns true if the selected engine is registered
+func (m MarkdownOptions) hasEngine() bool {
+	engines.rw.RLock()
+	defer engines.rw.RUnlock()
+
+	_, ok := engines.engines[m.engine()]
+	return ok
+}
+
+// Renderer returns the RenderMux of the selected engine
+func (m MarkdownOptions) Renderer() (RenderMux, error) {
+	name := m.engine()
+
+	engines.rw.RLock()
+	engine, ok := engines.engines[name]
+	engines.rw.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEngine, name)
+	}
+
+	return engine(m)
+}
+
+// blackfridayExtensions maps the extension names to the blackfriday v1 flags
+var blackfridayExtensions = map[string]int{
+	ExtTables:          blackfriday.EXTENSION_TABLES,
+	ExtFencedCode:      blackfriday.EXTENSION_FENCED_CODE,
+	ExtAutolinks:       blackfriday.EXTENSION_AUTOLINK,
+	ExtStrikethrough:   blackfriday.EXTENSION_STRIKETHROUGH,
+	ExtFootnotes:       blackfriday.EXTENSION_FOOTNOTES,
+	ExtDefinitionLists: blackfriday.EXTENSION_DEFINITION_LISTS,
+	ExtHeadingIDs:      blackfriday.EXTENSION_AUTO_HEADER_IDS,
+}
+
+// blackfridayEngine renders with blackfriday v1, the default options render exactly as blackfriday.MarkdownCommon
+func blackfridayEngine(m MarkdownOptions) (RenderMux, error) {
+	if m.Extensions == nil && !m.HardWraps && !m.XHTML {
+		return blackfriday.MarkdownCommon, nil
+	}
+
+	smartypants := blackfriday.HTML_USE_SMARTYPANTS | blackfriday.HTML_SMARTYPANTS_FRACTIONS |
+		blackfriday.HTML_SMARTYPANTS_DASHES | blackfriday.HTML_SMARTYPANTS_LATEX_DASHES
+
+	var flags int
+	exts := blackfriday.EXTENSION_NO_INTRA_EMPHASIS | blackfriday.EXTENSION_SPACE_HEADERS | blackfriday.EXTENSION_BACKSLASH_LINE_BREAK
+
+	//without extensions we keep the MarkdownCommon set
+	if m.Extensions == nil {
+		exts |= blackfriday.EXTENSION_TABLES | blackfriday.EXTENSION_FENCED_CODE | blackfriday.EXTENSION_AUTOLINK |
+			blackfriday.EXTENSION_STRIKETHROUGH | blackfriday.EXTENSION_HEADER_IDS | blackfriday.EXTENSION_DEFINITION_LISTS
+		flags |= smartypants | blackfriday.HTML_USE_XHTML
+	}
+
+	for _, name := range m.Extensions {
+		switch flag, ok := blackfridayExtensions[name]; {
+		case ok:
+			exts |= flag
+		case name == ExtTypographer:
+			flags |= smartypants
+		default:
+			return nil, fmt.Errorf("%w: blackfriday %q", ErrUnknownExtension, name)
+		}
+	}
+
+	if m.XHTML {
+		flags |= blackfriday.HTML_USE_XHTML
+	}
+
+	if m.HardWraps {
+		exts |= blackfriday.EXTENSION_HARD_LINE_BREAK
+	}
+
+	renderer := blackfriday.HtmlRenderer(flags, "", "")
+
+	return func(data []byte) []byte {
+		return blackfriday.Markdown(data, renderer, exts)
+	}, nil
+}
+
+// goldmarkExtensions maps the extension names to the goldmark extensions
+var goldmarkExtensions = map[string]goldmark.Extender{
+	ExtTables:          extension.Table,
+	ExtAutolinks:       extension.Linkify,
+	ExtStrikethrough:   extension.Strikethrough,
+	ExtTaskLists:       extension.TaskList,
+	ExtFootnotes:       extension.Footnote,
+	ExtDefinitionLists: extension.DefinitionList,
+	ExtTypographer:     extension.Typographer,
+}
+
+// goldmarkEngine returns a CommonMark compliant engine using goldmark with the default extensions
+func goldmarkEngine(defaults []string) MarkdownEngine {
+	return func(m MarkdownOptions) (RenderMux, error) {
+		names := m.Extensions
+
+		if names == nil {
+			names = defaults
+		}
+
+		var parserOptions []parser.Option
+		var exts []goldmark.Extender
+
+		for _, name := range names {
+			switch ext, ok := goldmarkExtensions[name]; {
+			case ok:
+				exts = append(exts, ext)
+			case name == ExtHeadingIDs:
+				parserOptions = append(parserOptions, parser.WithAutoHeadingID())
+			case name == ExtFencedCode:
+				//fenced code blocks are part of commonmark
+			default:
+				return nil, fmt.Errorf("%w: goldmark %q", ErrUnknownExtension, name)
+			}
+		}
+
+		var htmlOptions []renderer.Option
+
+		if m.HardWraps {
+			htmlOptions = append(htmlOptions, html.WithHardWraps())
+		}
+
+		if m.XHTML {
+			htmlOptions = append(htmlOptions, html.WithXHTML())
+		}
+
+		if m.Unsafe {
+			htmlOptions = append(htmlOptions, html.WithUnsafe())
+		}
+
+		md := goldmark.New(
+			goldmark.WithExtensions(exts...),
+			goldmark.WithParserOptions(parserOptions...),
+			goldmark.WithRendererOptions(htmlOptions...),
+		)
+
+		return func(data []byte) []byte {
+			var buf bytes.Buffer
+
+			//a RenderMux can not fail, so the error is logged along with whatever was rendered before it
+			if err := md.Convert(data, &buf); err != nil {
+				logs.Error(m.Logger, "markdown render failed", logs.Task("Markdown"), logs.F("engine", m.engine()), logs.Err(err))
+			}
+
+			return buf.Bytes()
+		}, nil
+	}
+}
+
+// markdownRenderer returns the RenderMux of the options, sanitized with the policy if it is not nil
+func markdownRenderer(m MarkdownOptions, policy *bluemonday.Policy) (RenderMux, error) {
+	render, err := m.Renderer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if policy == nil {
+		return render, nil
+	}
+
+	return func(data []byte) []byte {
+		return policy.SanitizeBytes(render(data))
+	}, nil
+}
+
+// Markdown returns a ByteRenderer rendering the markdown of the *RenderFile it receives with the engine selected by
+// the options
+func Markdown(m MarkdownOptions) (flux.Reactor, error) {
+	render, err := m.Renderer()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewByteRenderer(render)
+}
@@ -0,0 +1,62 @@
+package builders
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+	"github.com/russross/blackfriday"
+)
+
+func TestMarkdownEngines(t *testing.T) {
+	source := []byte("# Title\n\n| a | b |\n|---|---|\n| 1 | 2 |\n\n- [x] done\n\n~~old~~\n")
+
+	render, err := MarkdownOptions{}.Renderer()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create the default engine: %s", err)
+	}
+
+	if string(render(source)) != string(blackfriday.MarkdownCommon(source)) {
+		flux.FatalFailed(t, "Expected the default engine to render as blackfriday.MarkdownCommon")
+	}
+
+	render, err = MarkdownOptions{Engine: "gfm"}.Renderer()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create the gfm engine: %s", err)
+	}
+
+	gfm := string(render(source))
+
+	for _, expected := range []string{"<table>", `<input checked="" disabled="" type="checkbox"`, "<del>old</del>"} {
+		if !strings.Contains(gfm, expected) {
+			flux.FatalFailed(t, "Expected gfm output to contain %q: %s", expected, gfm)
+		}
+	}
+
+	render, err = MarkdownOptions{Engine: "commonmark", Extensions: []string{ExtHeadingIDs}}.Renderer()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create the commonmark engine: %s", err)
+	}
+
+	if out := string(render(source)); !strings.Contains(out, `<h1 id="title">`) || strings.Contains(out, "<table>") {
+		flux.FatalFailed(t, "Expected commonmark with heading ids only: %s", out)
+	}
+
+	if _, err = (MarkdownOptions{Extensions: []string{ExtTaskLists}}).Renderer(); !errors.Is(err, ErrUnknownExtension) {
+		flux.FatalFailed(t, "Expected blackfriday to reject task lists: %s", err)
+	}
+
+	if _, err = (MarkdownOptions{Engine: "nope"}).Renderer(); !errors.Is(err, ErrUnknownEngine) {
+		flux.FatalFailed(t, "Expected an unknown engine error")
+	}
+
+	if err := (MarkConfig{Markdown: MarkdownOptions{Engine: "nope"}}).Validate(); err == nil || !err.(*ValidationError).Has("Markdown.Engine") {
+		flux.FatalFailed(t, "Expected the unknown engine to fail validation: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully rendered with the markdown engines")
+}
@@ -0,0 +1,557 @@
+package builders
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/go-fsnotify/fsnotify"
+	"github.com/influx6/assets"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+)
+
+// OutputEntry records the output a stream wrote for a source along with what it was rendered from, so incremental
+// runs can tell which sources changed since
+type OutputEntry struct {
+	Output  string `json:"output"`            // Output is the path of the output file
+	Hash    string `json:"hash,omitempty"`    // Hash is the sha256 hash of the source
+	Deps    string `json:"deps,omitempty"`    // Deps is the fingerprint of the render options and templates
+	Slug    string `json:"slug,omitempty"`    // Slug is the slug of the front matter of the source
+	ModTime int64  `json:"modtime,omitempty"` // ModTime is the modification time of the source in unix nanoseconds
+	Size    int64  `json:"size,omitempty"`    // Size is the size of the source
+}
+
+// OutputState maps the absolute path of a source file to the OutputEntry a stream last wrote for it
+type OutputState map[string]OutputEntry
+
+// ReadOutputState loads the OutputState stored at the given path, returning an empty state if the file does not exist
+func ReadOutputState(path string) (OutputState, error) {
+	state := make(OutputState)
+
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// Write stores the state at the given path, creating its directory if needed
+func (o OutputState) Write(path string) error {
+	data, err := json.MarshalIndent(o, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// Record sets the entry of the source and returns its previous output if it changed and no other source claims it,
+// which is the stale target of a renamed output
+func (o OutputState) Record(source string, entry OutputEntry) string {
+	previous, ok := o[source]
+	o[source] = entry
+
+	if !ok || previous.Output == entry.Output || o.claimed(previous.Output) {
+		return ""
+	}
+
+	return previous.Output
+}
+
+// Orphans drops the sources missing from the given set and returns their outputs sorted, leaving out the outputs
+// another source still claims
+func (o OutputState) Orphans(sources map[string]bool) []string {
+	var gone []string
+
+	for source, entry := range o {
+		if !sources[source] {
+			delete(o, source)
+			gone = append(gone, entry.Output)
+		}
+	}
+
+	var orphans []string
+
+	for _, output := range gone {
+		if !o.claimed(output) {
+			orphans = append(orphans, output)
+		}
+	}
+
+	sort.Strings(orphans)
+	return orphans
+}
+
+// claimed returns true if a source of the state writes to the output
+func (o OutputState) claimed(output string) bool {
+	for _, entry := range o {
+		if entry.Output == output {
+			return true
+		}
+	}
+	return false
+}
+
+// hashHex returns the hex encoded sha256 hash of the data
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// fingerprint returns a hash of the render options of the config along with the files of its layout templates and
+// policy, a change to any of them changes the output of every page
+func (m MarkStreamConfig) fingerprint() (string, error) {
+	h := sha256.New()
+
+	options := []interface{}{m.SaveDir, m.Ext, m.Sanitize, m.Policy, m.Markdown, m.Highlight, m.TOC, m.Links, m.Drafts,
+		m.Layout.TemplateDir, m.Layout.Layout, m.Layout.Exts}
+
+	if err := json.NewEncoder(h).Encode(options); err != nil {
+		return "", err
+	}
+
+	var files []string
+
+	if m.Policy.File != "" {
+		files = append(files, m.Policy.File)
+	}
+
+	if m.Layout.TemplateDir != "" {
+		err := filepath.Walk(m.Layout.TemplateDir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() {
+				files = append(files, path)
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s %s\n", file, hashHex(data))
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// sourceEntry is the modification time and size of a source along with whether its front matter left it out of the
+// last run, eg. as a draft
+type sourceEntry struct {
+	modTime int64
+	size    int64
+	skip    bool
+}
+
+// sourceCache remembers the sources listed by the runs of a MarkFridayStream so a source is only read and its front
+// matter parsed again once its modification time or size changes
+type sourceCache struct {
+	mu      sync.Mutex
+	entries map[string]sourceEntry
+}
+
+func newSourceCache() *sourceCache {
+	return &sourceCache{entries: make(map[string]sourceEntry)}
+}
+
+// sources returns the absolute paths of the files of the input dir a MarkFridayStream run renders, drafts are left
+// out unless rendered
+func (c *sourceCache) sources(dir string, validator assets.PathValidator, drafts bool) (map[string]bool, error) {
+	var sources = make(map[string]bool)
+	var entries = make(map[string]sourceEntry)
+
+	dir, err := filepath.Abs(dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || (validator != nil && !validator(path, info)) {
+			return nil
+		}
+
+		entry, ok := c.entries[path]
+
+		if !ok || entry.modTime != info.ModTime().UnixNano() || entry.size != info.Size() {
+			data, err := ioutil.ReadFile(path)
+
+			if err != nil {
+				return err
+			}
+
+			meta, _ := ParseFrontMatter(data)
+
+			entry = sourceEntry{
+				modTime: info.ModTime().UnixNano(),
+				size:    info.Size(),
+				skip:    IsDraft(meta) && !drafts,
+			}
+		}
+
+		entries[path] = entry
+
+		if !entry.skip {
+			sources[path] = true
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	//deleted sources drop out of the cache along with the walk
+	c.entries = entries
+
+	return sources, nil
+}
+
+// outputTracker keeps the OutputState of a MarkFridayStream in its state file. With cleanup set it removes the
+// outputs of deleted sources and the stale targets of renamed outputs through a fs.FileRemover, with incremental set
+// it scans the sources of each run for the ones which need rendering
+type outputTracker struct {
+	file        string
+	cleanup     bool
+	incremental bool
+	remover     flux.Reactor
+	mu          sync.Mutex
+	state       OutputState
+	scanned     map[string]OutputEntry
+	dirty       map[string]bool
+}
+
+func newOutputTracker(file string, cleanup, incremental bool) (*outputTracker, error) {
+	state, err := ReadOutputState(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputTracker{
+		file:        file,
+		cleanup:     cleanup,
+		incremental: incremental,
+		remover:     fs.FileRemover(),
+		state:       state,
+		scanned:     make(map[string]OutputEntry),
+	}, nil
+}
+
+// record sets the output of the source along with the fingerprint its run scanned, removing its previous output if
+// the output path changed
+func (t *outputTracker) record(source, output, id string) error {
+	source, _ = filepath.Abs(source)
+	output, _ = filepath.Abs(output)
+
+	t.mu.Lock()
+
+	entry := t.scanned[source]
+	entry.Output = output
+
+	if t.state[source] == entry {
+		t.mu.Unlock()
+		return nil
+	}
+
+	stale := t.state.Record(source, entry)
+	err := t.state.Write(t.file)
+	t.mu.Unlock()
+
+	if stale != "" && t.cleanup {
+		t.remove(stale, id)
+	}
+
+	return err
+}
+
+// prune drops the sources missing from the given set, removing their outputs with cleanup set
+func (t *outputTracker) prune(sources map[string]bool, id string) error {
+	t.mu.Lock()
+	before := len(t.state)
+	orphans := t.state.Orphans(sources)
+
+	var err error
+
+	if len(t.state) != before {
+		err = t.state.Write(t.file)
+	}
+
+	t.mu.Unlock()
+
+	if t.cleanup {
+		for _, orphan := range orphans {
+			t.remove(orphan, id)
+		}
+	}
+
+	return err
+}
+
+// scan compares the sources with their entries and marks the ones which need rendering as dirty, these are new
+// sources, sources whose hash or deps changed, sources whose output is missing and the changed paths. Sources whose
+// modification time and size did not change are not read again. When links are rewritten a new source or a changed
+// slug marks every source as dirty since the links of the other pages point to its output
+func (t *outputTracker) scan(sources map[string]bool, deps string, rewrite bool, changed map[string]bool) error {
+	var scanned = make(map[string]OutputEntry)
+	var dirty = make(map[string]bool)
+	var relink, touched bool
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for source := range sources {
+		info, err := os.Stat(source)
+
+		if err != nil {
+			return err
+		}
+
+		previous, known := t.state[source]
+
+		entry := previous
+		entry.Deps = deps
+		entry.ModTime = info.ModTime().UnixNano()
+		entry.Size = info.Size()
+
+		if !known || changed[source] || entry.ModTime != previous.ModTime || entry.Size != previous.Size {
+			data, err := ioutil.ReadFile(source)
+
+			if err != nil {
+				return err
+			}
+
+			meta, _ := ParseFrontMatter(data)
+			entry.Hash = hashHex(data)
+			entry.Slug = MetaString(meta, "slug")
+		}
+
+		scanned[source] = entry
+
+		if !known || entry.Slug != previous.Slug {
+			relink = true
+		}
+
+		if !known || changed[source] || entry.Hash != previous.Hash || entry.Deps != previous.Deps {
+			dirty[source] = true
+		} else if _, err := os.Stat(previous.Output); err != nil {
+			dirty[source] = true
+		} else if entry != previous {
+			//touched sources keep their output but their new modification time saves hashing them again
+			t.state[source] = entry
+			touched = true
+		}
+	}
+
+	if rewrite && relink {
+		for source := range sources {
+			dirty[source] = true
+		}
+	}
+
+	t.scanned = scanned
+	t.dirty = dirty
+
+	if touched {
+		return t.state.Write(t.file)
+	}
+
+	return nil
+}
+
+// pending returns the sources the current run renders
+func (t *outputTracker) pending() map[string]bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var pages = make(map[string]bool)
+
+	for source := range t.dirty {
+		pages[source] = true
+	}
+
+	return pages
+}
+
+// needs returns true if the source has to be rendered in the current run
+func (t *outputTracker) needs(source string) bool {
+	if !t.incremental {
+		return true
+	}
+
+	source, _ = filepath.Abs(source)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.dirty[source]
+}
+
+// remove sends the removal of the output to the remover if it still exists
+func (t *outputTracker) remove(output, id string) {
+	if _, err := os.Stat(output); err != nil {
+		return
+	}
+
+	t.remover.Send(&fs.RemoveFile{Path: output, ID: id})
+}
+
+// changedPath returns the absolute path of the file changed by a fs.Watch or fs.WatchSet event, ok is false for other
+// signals
+func changedPath(data interface{}) (string, bool) {
+	ev, ok := fs.Unwrap(data).(fsnotify.Event)
+
+	if !ok {
+		return "", false
+	}
+
+	path, err := filepath.Abs(ev.Name)
+	return path, err == nil
+}
+
+// streamObserver follows the runs of a MarkFridayStream, it is told the sources and the pages rendered by each run
+// and receives every rendered file before its layout is executed
+type streamObserver interface {
+	// missing returns the sources an incremental run has to render even if they did not change
+	missing(sources map[string]bool) []string
+
+	// start begins a run of the id rendering the pages out of the sources
+	start(id string, sources, pages map[string]bool)
+
+	// rendered receives a rendered file and returns the file passed on to the layout
+	rendered(rf *RenderFile) *RenderFile
+
+	// writer returns the reactor whose replies are sent down the stream
+	writer() flux.Reactor
+}
+
+// streamHooks are the optional trackers of a MarkFridayStream
+type streamHooks struct {
+	checker  *linkChecker
+	tracker  *outputTracker
+	observer streamObserver
+}
+
+// streamGate returns the first stage of a MarkFridayStream which gives the signal a correlation ID if it has none,
+// prunes the outputs of deleted sources and scans for the changed ones with the tracker, then starts the run with the
+// checker and the observer before passing it on
+func streamGate(m MarkStreamConfig, hooks streamHooks) flux.Reactor {
+	tracker := hooks.tracker
+	cache := newSourceCache()
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		id := fs.IDOf(data)
+
+		if id == "" {
+			id = fs.NewID()
+			data = &fs.Envelope{ID: id, Data: fs.Unwrap(data)}
+		}
+
+		sources, err := cache.sources(m.InputDir, m.Validator, m.Drafts)
+
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+
+		pages := sources
+
+		if tracker != nil {
+			if err := tracker.prune(sources, id); err != nil {
+				root.ReplyError(err)
+				return
+			}
+		}
+
+		if tracker != nil && tracker.incremental {
+			deps, err := m.fingerprint()
+
+			if err != nil {
+				root.ReplyError(err)
+				return
+			}
+
+			var changed = make(map[string]bool)
+
+			if path, ok := changedPath(data); ok {
+				changed[path] = true
+			}
+
+			if hooks.observer != nil {
+				for _, source := range hooks.observer.missing(sources) {
+					changed[source] = true
+				}
+			}
+
+			if err := tracker.scan(sources, deps, m.Links.Rewrite, changed); err != nil {
+				root.ReplyError(err)
+				return
+			}
+
+			pages = tracker.pending()
+		}
+
+		if hooks.checker != nil {
+			hooks.checker.start(id, sources)
+		}
+
+		if hooks.observer != nil {
+			hooks.observer.start(id, sources, pages)
+		}
+
+		root.Reply(data)
+	}))
+}
+
+// changedSources returns the stage of an incremental MarkFridayStream which only passes on the paths of the sources
+// the tracker marked as dirty
+func changedSources(tracker *outputTracker) flux.Reactor {
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		if path, ok := fs.Unwrap(data).(string); ok && !tracker.needs(path) {
+			return
+		}
+
+		root.Reply(data)
+	}))
+}
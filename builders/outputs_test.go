@@ -0,0 +1,255 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestOutputState(t *testing.T) {
+	state := OutputState{
+		"/src/a.md": {Output: "/out/a.html"},
+		"/src/b.md": {Output: "/out/b.html"},
+		"/src/c.md": {Output: "/out/c.html"},
+	}
+
+	if stale := state.Record("/src/a.md", OutputEntry{Output: "/out/a.html", Hash: "1"}); stale != "" {
+		flux.FatalFailed(t, "Expected an unchanged output to not be stale: %q", stale)
+	}
+
+	if stale := state.Record("/src/a.md", OutputEntry{Output: "/out/intro.html"}); stale != "/out/a.html" {
+		flux.FatalFailed(t, "Expected the previous output to be stale: %q", stale)
+	}
+
+	if stale := state.Record("/src/d.md", OutputEntry{Output: "/out/d.html"}); stale != "" {
+		flux.FatalFailed(t, "Expected a new source to have no stale output: %q", stale)
+	}
+
+	//b takes over the output of c so it is not stale when c is removed
+	state.Record("/src/b.md", OutputEntry{Output: "/out/c.html"})
+
+	orphans := state.Orphans(map[string]bool{"/src/a.md": true, "/src/b.md": true, "/src/d.md": true})
+
+	if len(orphans) != 0 {
+		flux.FatalFailed(t, "Expected claimed outputs to not be orphans: %+v", orphans)
+	}
+
+	if _, ok := state["/src/c.md"]; ok {
+		flux.FatalFailed(t, "Expected the missing source to be dropped: %+v", state)
+	}
+
+	orphans = state.Orphans(map[string]bool{"/src/b.md": true})
+
+	if strings.Join(orphans, "|") != "/out/d.html|/out/intro.html" || len(state) != 1 {
+		flux.FatalFailed(t, "Unexpected orphans: %+v %+v", orphans, state)
+	}
+
+	flux.LogPassed(t, "Successfully tracked stale and orphaned outputs")
+}
+
+func TestReadOutputState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-outputs")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "out", ".markfriday.json")
+
+	state, err := ReadOutputState(file)
+
+	if err != nil || len(state) != 0 {
+		flux.FatalFailed(t, "Expected an empty state for a missing file: %+v %s", state, err)
+	}
+
+	state["/src/a.md"] = OutputEntry{Output: "/out/a.html", Hash: "abc"}
+
+	if err := state.Write(file); err != nil {
+		flux.FatalFailed(t, "Unable to write state: %s", err)
+	}
+
+	loaded, err := ReadOutputState(file)
+
+	if err != nil || loaded["/src/a.md"] != state["/src/a.md"] {
+		flux.FatalFailed(t, "Expected the state to be loaded: %+v %s", loaded, err)
+	}
+
+	flux.LogPassed(t, "Successfully stored the output state")
+}
+
+func TestOutputScan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-scan")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	out := filepath.Join(dir, "out")
+	os.MkdirAll(src, 0755)
+	os.MkdirAll(out, 0755)
+
+	for _, name := range []string{"a", "b"} {
+		ioutil.WriteFile(filepath.Join(src, name+".md"), []byte("# "+name+"\n"), 0644)
+	}
+
+	tracker := &outputTracker{file: filepath.Join(out, ".markfriday.json"), incremental: true, state: make(OutputState)}
+	cache := newSourceCache()
+
+	//render renders the dirty sources of a scan by recording their outputs
+	render := func(changed map[string]bool, deps string, rewrite bool) []string {
+		sources, err := cache.sources(src, nil, false)
+
+		if err != nil {
+			flux.FatalFailed(t, "Unable to list sources: %s", err)
+		}
+
+		if err := tracker.scan(sources, deps, rewrite, changed); err != nil {
+			flux.FatalFailed(t, "Unable to scan sources: %s", err)
+		}
+
+		var rendered []string
+
+		for _, name := range []string{"a", "b"} {
+			source := filepath.Join(src, name+".md")
+
+			if tracker.needs(source) {
+				output := filepath.Join(out, name+".html")
+				ioutil.WriteFile(output, []byte(name), 0644)
+				tracker.record(source, output, "")
+				rendered = append(rendered, name)
+			}
+		}
+
+		return rendered
+	}
+
+	if rendered := render(nil, "v1", false); strings.Join(rendered, ",") != "a,b" {
+		flux.FatalFailed(t, "Expected every source to render first: %+v", rendered)
+	}
+
+	if rendered := render(nil, "v1", false); len(rendered) != 0 {
+		flux.FatalFailed(t, "Expected unchanged sources to be skipped: %+v", rendered)
+	}
+
+	ioutil.WriteFile(filepath.Join(src, "b.md"), []byte("# b changed\n"), 0644)
+
+	if rendered := render(nil, "v1", false); strings.Join(rendered, ",") != "b" {
+		flux.FatalFailed(t, "Expected only the changed source to render: %+v", rendered)
+	}
+
+	if rendered := render(map[string]bool{filepath.Join(src, "a.md"): true}, "v1", false); strings.Join(rendered, ",") != "a" {
+		flux.FatalFailed(t, "Expected the source of the change event to render: %+v", rendered)
+	}
+
+	os.Remove(filepath.Join(out, "a.html"))
+
+	if rendered := render(nil, "v1", false); strings.Join(rendered, ",") != "a" {
+		flux.FatalFailed(t, "Expected the source of a missing output to render: %+v", rendered)
+	}
+
+	if rendered := render(nil, "v2", false); strings.Join(rendered, ",") != "a,b" {
+		flux.FatalFailed(t, "Expected a deps change to render every source: %+v", rendered)
+	}
+
+	ioutil.WriteFile(filepath.Join(src, "a.md"), []byte("---\nslug: intro\n---\n# a\n"), 0644)
+
+	if rendered := render(nil, "v2", true); strings.Join(rendered, ",") != "a,b" {
+		flux.FatalFailed(t, "Expected a slug change to render every source when links are rewritten: %+v", rendered)
+	}
+
+	state, err := ReadOutputState(tracker.file)
+
+	if err != nil || len(state) != 2 || state[filepath.Join(src, "a.md")].Slug != "intro" {
+		flux.FatalFailed(t, "Expected the scanned entries to be stored: %+v %s", state, err)
+	}
+
+	flux.LogPassed(t, "Successfully rendered only the changed sources")
+}
+
+func TestSourceCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-sources")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	dir, _ = filepath.Abs(dir)
+	page := filepath.Join(dir, "page.md")
+	ioutil.WriteFile(page, []byte("---\ndraft: true \n---\n# a\n"), 0644)
+
+	cache := newSourceCache()
+
+	if sources, err := cache.sources(dir, nil, false); err != nil || sources[page] {
+		flux.FatalFailed(t, "Expected the draft to be left out: %+v %s", sources, err)
+	}
+
+	//same size and modification time so the cached draft status is kept without reading the file
+	info, _ := os.Stat(page)
+	ioutil.WriteFile(page, []byte("---\ndraft: false\n---\n# a\n"), 0644)
+	os.Chtimes(page, info.ModTime(), info.ModTime())
+
+	if sources, err := cache.sources(dir, nil, false); err != nil || sources[page] {
+		flux.FatalFailed(t, "Expected the unchanged source to not be parsed again: %+v %s", sources, err)
+	}
+
+	ioutil.WriteFile(page, []byte("---\ndraft: false\n---\n# page\n"), 0644)
+
+	if sources, err := cache.sources(dir, nil, false); err != nil || !sources[page] {
+		flux.FatalFailed(t, "Expected the changed source to be parsed again: %+v %s", sources, err)
+	}
+
+	os.Remove(page)
+
+	if sources, err := cache.sources(dir, nil, false); err != nil || len(sources) != 0 || len(cache.entries) != 0 {
+		flux.FatalFailed(t, "Expected the deleted source to leave the cache: %+v %s", sources, err)
+	}
+
+	flux.LogPassed(t, "Successfully listed sources from the cache")
+}
+
+func TestStreamFingerprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-fingerprint")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	layout := filepath.Join(dir, "default.html")
+	ioutil.WriteFile(layout, []byte("{{.Content}}"), 0644)
+
+	config := MarkStreamConfig{InputDir: dir, Layout: LayoutConfig{TemplateDir: dir}}
+	first, err := config.fingerprint()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to fingerprint config: %s", err)
+	}
+
+	ioutil.WriteFile(layout, []byte("<main>{{.Content}}</main>"), 0644)
+
+	second, _ := config.fingerprint()
+
+	if first == second {
+		flux.FatalFailed(t, "Expected a template change to change the fingerprint")
+	}
+
+	config.Ext = ".tmpl"
+
+	if third, _ := config.fingerprint(); third == second {
+		flux.FatalFailed(t, "Expected an option change to change the fingerprint")
+	}
+
+	flux.LogPassed(t, "Successfully fingerprinted the render options and templates")
+}
@@ -0,0 +1,473 @@
+package builders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-fsnotify/fsnotify"
+	"github.com/influx6/assets"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+	"github.com/influx6/reactors/metrics"
+)
+
+// PackageWatchConfig provides the configuration for PackageWatcherWith
+type PackageWatchConfig struct {
+	Package   string
+	Dir       string               // Optional: directory go list resolves the package from eg. within its module, defaults to the current directory
+	Validator assets.PathValidator // Optional: filters the paths to be watched
+	Git       bool                 // Optional: if true, only the files tracked or untracked but not ignored in the git repository of the package are watched
+	Since     string               // Optional: if set, instead of watching, every signal sends the package files changed since the git ref eg. HEAD~1
+	Correlate bool                 // Optional: if true the changes are sent within an *fs.Envelope carrying their correlation ID
+	Logger    logs.Logger          // Optional: Logger receives the change events and watcher status, defaults to the global logs logger
+	Metrics   metrics.Recorder     // Optional: Metrics counts the change events and records their spans, defaults to the global metrics recorder
+}
+
+// GoModule is the module of a GoPackage as listed by go list
+type GoModule struct {
+	Path    string
+	Version string
+	Main    bool
+	Dir     string
+	GoMod   string
+	Replace *GoModule
+}
+
+// GoPackage is the part of the package information listed by `go list -json` used to resolve the files of a package
+type GoPackage struct {
+	ImportPath string
+	Dir        string
+	Standard   bool
+	Module     *GoModule
+
+	GoFiles    []string
+	CgoFiles   []string
+	CFiles     []string
+	CXXFiles   []string
+	MFiles     []string
+	HFiles     []string
+	FFiles     []string
+	SFiles     []string
+	SysoFiles  []string
+	EmbedFiles []string
+	Imports    []string
+
+	Error *struct {
+		Err string
+	}
+}
+
+// Local returns true if the package belongs to a main or workspace module or to a module replaced by a local directory,
+// outside of modules any package not from the standard library is local
+func (g GoPackage) Local() bool {
+	if g.Standard {
+		return false
+	}
+
+	if g.Module == nil {
+		return true
+	}
+
+	return g.Module.Main || (g.Module.Replace != nil && g.Module.Replace.Version == "")
+}
+
+// Files returns the absolute paths of the go, cgo, assembly and embedded files of the package
+func (g GoPackage) Files() []string {
+	var files []string
+
+	for _, list := range [][]string{g.GoFiles, g.CgoFiles, g.CFiles, g.CXXFiles, g.MFiles, g.HFiles, g.FFiles, g.SFiles, g.SysoFiles, g.EmbedFiles} {
+		for _, file := range list {
+			files = append(files, filepath.Join(g.Dir, file))
+		}
+	}
+
+	return files
+}
+
+// ListPackages runs `go list -e -deps -json` for the patterns in the directory and returns the listed packages, the
+// packages matching the patterns come after their dependencies
+func ListPackages(dir string, patterns ...string) ([]GoPackage, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.Command("go", append([]string{"list", "-e", "-deps", "-json"}, patterns...)...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("go list failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	var pkgs []GoPackage
+
+	decoder := json.NewDecoder(&stdout)
+
+	for {
+		var pkg GoPackage
+
+		if err := decoder.Decode(&pkg); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+// goEnv returns the value of the go environment variable in the directory
+func goEnv(dir, name string) string {
+	cmd := exec.Command("go", "env", name)
+	cmd.Dir = dir
+
+	out, err := cmd.Output()
+
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+// moduleFiles are the files whose changes may change the dependencies of a package
+var moduleFiles = map[string]bool{"go.mod": true, "go.sum": true, "go.work": true, "go.work.sum": true}
+
+// packageFiles are the resolved files of a package and its local dependencies
+type packageFiles struct {
+	root    string            // directory of the package itself
+	dirs    map[string]bool   // directories of the local packages
+	files   map[string]bool   // files of the local packages
+	imports map[string]string // imports of each go file
+	modules map[string]bool   // go.mod, go.sum and go.work files of the local modules
+}
+
+// resolvePackage lists the package from the directory and returns the files of its local packages
+func resolvePackage(dir, pkg string) (*packageFiles, error) {
+	if dir == "" {
+		dir = "."
+	}
+
+	pkgs, err := ListPackages(dir, pkg)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("go list found no package for %q", pkg)
+	}
+
+	//the package itself is listed after all its dependencies
+	target := pkgs[len(pkgs)-1]
+
+	if target.Error != nil {
+		return nil, fmt.Errorf("go list: %s", target.Error.Err)
+	}
+
+	//the paths are kept with their symlinks evaluated to match those of the git root and file set
+	resolved := &packageFiles{
+		root:    realPath(target.Dir),
+		dirs:    make(map[string]bool),
+		files:   make(map[string]bool),
+		imports: make(map[string]string),
+		modules: make(map[string]bool),
+	}
+
+	for _, p := range pkgs {
+		if !p.Local() || p.Dir == "" {
+			continue
+		}
+
+		resolved.dirs[realPath(p.Dir)] = true
+
+		for _, file := range p.Files() {
+			file = realPath(file)
+			resolved.files[file] = true
+
+			if filepath.Ext(file) == ".go" {
+				resolved.imports[file], _ = importsOf(file)
+			}
+		}
+
+		if p.Module != nil && p.Module.GoMod != "" {
+			gomod := realPath(p.Module.GoMod)
+			resolved.modules[gomod] = true
+			resolved.modules[filepath.Join(filepath.Dir(gomod), "go.sum")] = true
+		}
+	}
+
+	if work := goEnv(dir, "GOWORK"); work != "" && work != "off" {
+		work = realPath(work)
+		resolved.modules[work] = true
+		resolved.modules[work+".sum"] = true
+	}
+
+	return resolved, nil
+}
+
+// importsOf returns the sorted imports of the go file joined as a single string
+func importsOf(file string) (string, error) {
+	src, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.ImportsOnly)
+
+	if err != nil {
+		return "", err
+	}
+
+	var imports []string
+
+	for _, spec := range src.Imports {
+		imports = append(imports, spec.Path.Value)
+	}
+
+	sort.Strings(imports)
+	return strings.Join(imports, ","), nil
+}
+
+// restrict drops the directories, files and module files outside of the given root directory
+func (p *packageFiles) restrict(root string) {
+	within := func(path string) bool {
+		rel, err := filepath.Rel(root, path)
+		return err == nil && !strings.HasPrefix(rel, "..")
+	}
+
+	for _, set := range []map[string]bool{p.dirs, p.files, p.modules} {
+		for path := range set {
+			if !within(path) {
+				delete(set, path)
+			}
+		}
+	}
+}
+
+// paths returns the sorted directories and module files to be watched
+func (p *packageFiles) paths() []string {
+	var paths []string
+
+	for dir := range p.dirs {
+		paths = append(paths, dir)
+	}
+
+	for file := range p.modules {
+		if _, err := os.Stat(file); err == nil {
+			paths = append(paths, file)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+// has returns true for the package directories and files, module files and new go files within package directories
+func (p *packageFiles) has(path string) bool {
+	abs := realPath(path)
+
+	if p.dirs[abs] || p.files[abs] || p.modules[abs] {
+		return true
+	}
+
+	return filepath.Ext(abs) == ".go" && p.dirs[filepath.Dir(abs)]
+}
+
+// changed returns true if a change of the path may change the dependencies of the package, which is the case for
+// module files, new or removed go files and go files whose imports changed
+func (p *packageFiles) changed(path string) bool {
+	abs := realPath(path)
+
+	if moduleFiles[filepath.Base(abs)] {
+		return true
+	}
+
+	if filepath.Ext(abs) != ".go" {
+		return false
+	}
+
+	before, known := p.imports[abs]
+
+	if !known {
+		return p.dirs[filepath.Dir(abs)]
+	}
+
+	if _, err := os.Stat(abs); err != nil {
+		return true
+	}
+
+	after, err := importsOf(abs)
+
+	//a file being written may not parse yet, its next write will be checked again
+	return err == nil && after != before
+}
+
+// validator returns a PathValidator passing the paths of the package which also pass the git set and the given
+// validator if any
+func (p *packageFiles) validator(vx assets.PathValidator, git *gitFileSet) assets.PathValidator {
+	return func(path string, info os.FileInfo) bool {
+		if !p.has(path) {
+			return false
+		}
+
+		if git != nil && !git.has(path) {
+			return false
+		}
+
+		if vx != nil {
+			return vx(path, info)
+		}
+
+		return true
+	}
+}
+
+// PackageWatcherWith returns a PackageWatcher for the config. The package and its transitive local dependencies are
+// resolved with `go list -deps -json`, following go.mod replace directives and workspaces, and only their go, cgo and
+// embedded files are watched. The dependencies are resolved again when a go.mod, go.sum or go.work file changes or
+// when the imports of a go file change.
+//
+// With Git set only the files git tracks or does not ignore in the repository of the package are watched. With Since
+// set, the returned task does not watch but sends the files of the package changed since the ref on every signal,
+// as GitChanges does
+func PackageWatcherWith(config PackageWatchConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	resolved, err := resolvePackage(config.Dir, config.Package)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !config.Git && config.Since == "" {
+		return packageWatcher(config, resolved, "", nil), nil
+	}
+
+	root, err := GitRoot(resolved.root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resolved.restrict(root)
+
+	if config.Since != "" {
+		return gitChanges(root, config.Since, config.Correlate, func(file string) bool {
+			return resolved.has(file) && (config.Validator == nil || config.Validator(file, nil))
+		}), nil
+	}
+
+	files, err := newGitFileSet(root)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return packageWatcher(config, resolved, root, files), nil
+}
+
+// packageWatcher returns the task watching the resolved files, replacing its fs.WatchSet whenever the dependencies
+// are resolved again, the gitRoot and git set are only given in Git mode
+func packageWatcher(config PackageWatchConfig, resolved *packageFiles, gitRoot string, git *gitFileSet) flux.Reactor {
+	var rw sync.Mutex
+	var watcher flux.Reactor
+	var resolving int32
+
+	mo := flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+		root.Reply(data)
+	})
+
+	var watch func(files *packageFiles)
+
+	//resolve lists the package again and swaps the watcher, changes during a resolve are covered by it
+	resolve := func() {
+		if !atomic.CompareAndSwapInt32(&resolving, 0, 1) {
+			return
+		}
+
+		flux.GoDefer("PackageWatcher.Resolve", func() {
+			defer atomic.StoreInt32(&resolving, 0)
+
+			//let the burst of writes of a save settle before listing
+			<-time.After(100 * time.Millisecond)
+
+			files, err := resolvePackage(config.Dir, config.Package)
+
+			if err != nil {
+				logs.Warn(config.Logger, "package resolve failed", logs.Task("PackageWatcher"), logs.Path(config.Package), logs.Err(err))
+				mo.ReplyError(err)
+				return
+			}
+
+			if gitRoot != "" {
+				files.restrict(gitRoot)
+			}
+
+			logs.Info(config.Logger, "package dependencies resolved", logs.Task("PackageWatcher"), logs.Path(config.Package), logs.F("packages", len(files.dirs)))
+			watch(files)
+		})
+	}
+
+	watch = func(files *packageFiles) {
+		ws := fs.WatchSet(fs.WatchSetConfig{
+			Path:      files.paths(),
+			Validator: files.validator(config.Validator, git),
+			Correlate: config.Correlate,
+			Logger:    config.Logger,
+			Metrics:   config.Metrics,
+		})
+
+		ws.React(func(_ flux.Reactor, err error, data interface{}) {
+			if err != nil {
+				mo.ReplyError(err)
+				return
+			}
+
+			if ev, ok := fs.Unwrap(data).(fsnotify.Event); ok && files.changed(ev.Name) {
+				resolve()
+			}
+
+			mo.Reply(data)
+		}, true)
+
+		rw.Lock()
+		old := watcher
+		watcher = ws
+		rw.Unlock()
+
+		if old != nil {
+			old.Close()
+		}
+	}
+
+	watch(resolved)
+
+	flux.GoDefer("PackageWatcher.Close", func() {
+		<-mo.CloseNotify()
+
+		rw.Lock()
+		defer rw.Unlock()
+
+		if watcher != nil {
+			watcher.Close()
+		}
+	})
+
+	return mo
+}
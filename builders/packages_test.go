@@ -0,0 +1,101 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+// moduleFixture creates a module whose main package imports a local lib package embedding a data file
+func moduleFixture(t *testing.T) string {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go is not installed")
+	}
+
+	dir, err := ioutil.TempDir("", "reactors-module")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	files := map[string]string{
+		"go.mod":       "module example.com/app\n\ngo 1.16\n",
+		"main.go":      "package main\n\nimport \"example.com/app/lib\"\n\nfunc main() { lib.Run() }\n",
+		"lib/lib.go":   "package lib\n\nimport _ \"embed\"\n\n//go:embed data.txt\nvar data string\n\n// Run does nothing\nfunc Run() {}\n",
+		"lib/data.txt": "data\n",
+		"docs/doc.md":  "# docs\n",
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644)
+	}
+
+	return dir
+}
+
+func TestResolvePackage(t *testing.T) {
+	dir := moduleFixture(t)
+	defer os.RemoveAll(dir)
+
+	resolved, err := resolvePackage(dir, ".")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to resolve package: %s", err)
+	}
+
+	if !sameFile(resolved.root, dir) || !resolved.has(dir) || !resolved.has(filepath.Join(dir, "lib")) || len(resolved.dirs) != 2 {
+		flux.FatalFailed(t, "Expected only the main and lib package dirs: %+s", resolved.dirs)
+	}
+
+	for _, file := range []string{"main.go", "lib/lib.go", "lib/data.txt", "go.mod"} {
+		if !resolved.has(filepath.Join(dir, file)) {
+			flux.FatalFailed(t, "Expected %q to be watched", file)
+		}
+	}
+
+	if resolved.has(filepath.Join(dir, "docs/doc.md")) {
+		flux.FatalFailed(t, "Expected docs to not be watched")
+	}
+
+	flux.LogPassed(t, "Successfully resolved package files: %+s", resolved.paths())
+}
+
+func TestPackageChanged(t *testing.T) {
+	dir := moduleFixture(t)
+	defer os.RemoveAll(dir)
+
+	resolved, err := resolvePackage(dir, ".")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to resolve package: %s", err)
+	}
+
+	main := filepath.Join(dir, "main.go")
+
+	if resolved.changed(filepath.Join(dir, "lib/data.txt")) {
+		flux.FatalFailed(t, "Expected embedded file changes to not resolve again")
+	}
+
+	ioutil.WriteFile(main, []byte("package main\n\nimport \"example.com/app/lib\"\n\nfunc main() {\n\tlib.Run()\n\tlib.Run()\n}\n"), 0644)
+
+	if resolved.changed(main) {
+		flux.FatalFailed(t, "Expected body changes to not resolve again")
+	}
+
+	ioutil.WriteFile(main, []byte("package main\n\nimport (\n\t\"fmt\"\n\n\t\"example.com/app/lib\"\n)\n\nfunc main() { fmt.Println(); lib.Run() }\n"), 0644)
+
+	if !resolved.changed(main) {
+		flux.FatalFailed(t, "Expected import changes to resolve again")
+	}
+
+	if !resolved.changed(filepath.Join(dir, "go.mod")) || !resolved.changed(filepath.Join(dir, "lib/new.go")) {
+		flux.FatalFailed(t, "Expected go.mod and new go files to resolve again")
+	}
+
+	flux.LogPassed(t, "Successfully detected dependency changes")
+}
@@ -3,13 +3,16 @@ package builders
 import (
 	"bytes"
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"runtime"
 	"strings"
+	"time"
+
+	"github.com/influx6/reactors/logs"
+	"github.com/influx6/reactors/metrics"
 )
 
 var multispaces = regexp.MustCompile(`\s+`)
@@ -18,7 +21,7 @@ var multispaces = regexp.MustCompile(`\s+`)
 func GoDeps(targetdir string) error {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("godeps.Error: %+s", err)
+			logs.Error(nil, "recovered panic", logs.Task("GoDeps"), logs.Path(targetdir), logs.F("panic", err))
 		}
 	}()
 
@@ -45,7 +48,7 @@ func GoDeps(targetdir string) error {
 func GoRun(cmd string) string {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("gorun.Error: %+s", err)
+			logs.Error(nil, "recovered panic", logs.Task("GoRun"), logs.F("command", cmd), logs.F("panic", err))
 		}
 	}()
 	var cmdline []string
@@ -81,7 +84,7 @@ func GobuildArgs(args []string) error {
 
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("gobuild.Error: %+s", err)
+			logs.Error(nil, "recovered panic", logs.Task("GobuildArgs"), logs.F("args", args), logs.F("panic", err))
 		}
 	}()
 
@@ -111,9 +114,15 @@ func GobuildArgs(args []string) error {
 
 // Gobuild runs the build process and returns true/false and an error, this works by building in the current root i.e cwd(current working directory)
 func Gobuild(dir, name string, args []string) error {
+	return gobuild(dir, name, args, "", nil, nil)
+}
+
+// gobuild runs Gobuild logging into the given logger and recording into the given recorder or the global ones if nil,
+// the build span is a child of the watch event of the correlation ID
+func gobuild(dir, name string, args []string, id string, logger logs.Logger, recorder metrics.Recorder) error {
 	defer func() {
 		if err := recover(); err != nil {
-			log.Printf("gobuild.Error: %+s", err)
+			logs.Error(logger, "recovered panic", logs.Task("Gobuild"), logs.Path(dir), logs.F("panic", err))
 		}
 	}()
 
@@ -131,21 +140,38 @@ func Gobuild(dir, name string, args []string) error {
 	cmd := exec.Command("go", cmdline[1:]...)
 	buf := bytes.NewBuffer([]byte{})
 
+	start := time.Now()
+	done := metrics.StartBuild(recorder, id, "Gobuild", metrics.L("path", target))
 	msg, err := cmd.CombinedOutput()
 
 	if !cmd.ProcessState.Success() {
-		return fmt.Errorf("go.build failed: %s: %s -> Msg: %s", buf.String(), err.Error(), msg)
+		err = fmt.Errorf("go.build failed: %s: %s -> Msg: %s", buf.String(), err.Error(), msg)
+		logs.Error(logger, "go build failed", logs.Task("Gobuild"), logs.Path(target), logs.Duration(time.Since(start)), logs.Err(err))
+		done(err)
+		return err
 	}
 
-	// fmt.Printf("go.build.Response(%+s): %s", cmdline, msg)
+	logs.Info(logger, "go build finished", logs.Task("Gobuild"), logs.Path(target), logs.Duration(time.Since(start)))
+	done(nil)
 
 	return nil
 }
 
 // RunCMD runs the a set of commands from a list while skipping any one-length command, panics if it gets an empty lists
 func RunCMD(cmds []string, done func()) chan bool {
-	if len(cmds) < 0 {
-		panic("commands list cant be empty")
+	relunch, err := RunCommands(cmds, done)
+
+	if err != nil {
+		panic(err)
+	}
+
+	return relunch
+}
+
+// RunCommands runs the a set of commands from a list while skipping any one-length command, returning ErrNoCommands if it gets an empty lists
+func RunCommands(cmds []string, done func()) (chan bool, error) {
+	if len(cmds) == 0 {
+		return nil, ErrNoCommands
 	}
 
 	var relunch = make(chan bool)
@@ -153,7 +179,7 @@ func RunCMD(cmds []string, done func()) chan bool {
 	go func() {
 		defer func() {
 			if err := recover(); err != nil {
-				log.Printf("cmdRun.Error: %+s", err)
+				logs.Error(nil, "recovered panic", logs.Task("RunCommands"), logs.F("panic", err))
 			}
 		}()
 
@@ -170,7 +196,7 @@ func RunCMD(cmds []string, done func()) chan bool {
 					continue
 				}
 
-				fmt.Printf("--> Running Commands %s\n", cmds)
+				logs.Info(nil, "running commands", logs.Task("RunCommands"), logs.F("commands", cmds))
 				for _, cox := range cmds {
 
 					cmd := strings.Split(cox, " ")
@@ -184,8 +210,11 @@ func RunCMD(cmds []string, done func()) chan bool {
 					cmdo.Stderr = os.Stderr
 
 					if err := cmdo.Start(); err != nil {
-						fmt.Printf("---> Error executing command: %s -> %s\n", cmd, err)
+						logs.Error(nil, "command failed to start", logs.Task("RunCommands"), logs.F("command", cox), logs.Err(err))
+						continue
 					}
+
+					logs.Info(nil, "command started", logs.Task("RunCommands"), logs.F("command", cox), logs.Pid(cmdo.Process.Pid))
 				}
 
 				if done != nil {
@@ -195,7 +224,7 @@ func RunCMD(cmds []string, done func()) chan bool {
 		}
 
 	}()
-	return relunch
+	return relunch, nil
 }
 
 // RunGo runs the generated binary file with the arguments expected
@@ -212,23 +241,12 @@ func RunGo(gofile string, args []string, done, stopped func()) chan bool {
 		cmdargs := append([]string{"run", gofile}, args...)
 		// cmdline = strings.Joinappend([]string{}, "go run", gofile)
 
-		var proc *os.Process
+		var proc *process
+		var started bool
 
 		for dosig := range relunch {
 			if proc != nil {
-				var err error
-
-				if runtime.GOOS == "windows" {
-					err = proc.Kill()
-				} else {
-					err = proc.Signal(os.Interrupt)
-				}
-
-				if err != nil {
-					fmt.Printf("---> Error in Sending Kill Signal %s\n", err)
-					proc.Kill()
-				}
-				proc.Wait()
+				proc.stop()
 				proc = nil
 			}
 
@@ -236,16 +254,9 @@ func RunGo(gofile string, args []string, done, stopped func()) chan bool {
 				continue
 			}
 
-			fmt.Printf("--> Starting cmd: %s\n", cmdargs)
-			cmd := exec.Command("go", cmdargs...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
-
-			if err := cmd.Start(); err != nil {
-				fmt.Printf("---> Error starting process: %s\n", err)
-			}
+			proc = startProcess("RunGo", gofile, exec.Command("go", cmdargs...), started, "", nil, nil)
+			started = true
 
-			proc = cmd.Process
 			if done != nil {
 				done()
 			}
@@ -260,27 +271,22 @@ func RunGo(gofile string, args []string, done, stopped func()) chan bool {
 
 // RunBin runs the generated binary file with the arguments expected
 func RunBin(binfile string, args []string, done, stopped func()) chan bool {
+	return runBin(binfile, args, done, stopped, nil, nil, nil)
+}
+
+// runBin runs RunBin logging into the given logger and recording into the given recorder or the global ones if nil,
+// cause returns the correlation ID of the signal which restarts the process if not nil
+func runBin(binfile string, args []string, done, stopped func(), logger logs.Logger, recorder metrics.Recorder, cause func() string) chan bool {
 	var relunch = make(chan bool)
 	go func() {
 		// binfile := fmt.Sprintf("%s/%s", bindir, bin)
 		// cmdline := append([]string{bin}, args...)
-		var proc *os.Process
+		var proc *process
+		var started bool
 
 		for dosig := range relunch {
 			if proc != nil {
-				var err error
-
-				if runtime.GOOS == "windows" {
-					err = proc.Kill()
-				} else {
-					err = proc.Signal(os.Interrupt)
-				}
-
-				if err != nil {
-					fmt.Printf("---> Error in Sending Kill Signal: %s\n", err)
-					proc.Kill()
-				}
-				proc.Wait()
+				proc.stop()
 				proc = nil
 			}
 
@@ -288,16 +294,15 @@ func RunBin(binfile string, args []string, done, stopped func()) chan bool {
 				continue
 			}
 
-			fmt.Printf("--> Starting bin: %s\n", binfile)
-			cmd := exec.Command(binfile, args...)
-			cmd.Stdout = os.Stdout
-			cmd.Stderr = os.Stderr
+			var id string
 
-			if err := cmd.Start(); err != nil {
-				fmt.Printf("---> Error starting process: %s -> %s\n", binfile, err)
+			if cause != nil {
+				id = cause()
 			}
 
-			proc = cmd.Process
+			proc = startProcess("RunBin", binfile, exec.Command(binfile, args...), started, id, logger, recorder)
+			started = true
+
 			if done != nil {
 				done()
 			}
@@ -309,3 +314,56 @@ func RunBin(binfile string, args []string, done, stopped func()) chan bool {
 	}()
 	return relunch
 }
+
+// process is a started command of RunGo or RunBin
+type process struct {
+	task    string
+	path    string
+	proc    *os.Process
+	started time.Time
+	logger  logs.Logger
+}
+
+// startProcess starts the command with the standard output and error of the current process, returning nil if it failed
+// to start, restart is true if a previous process of the task was started before
+func startProcess(task, path string, cmd *exec.Cmd, restart bool, id string, logger logs.Logger, recorder metrics.Recorder) *process {
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if restart {
+		metrics.Inc(recorder, metrics.ProcessRestarts, metrics.L("task", task))
+	}
+
+	span := metrics.StartSpan(recorder, id, "process.start", metrics.L("task", task), metrics.L("path", path))
+	err := cmd.Start()
+	span.End(err)
+
+	if err != nil {
+		logs.Error(logger, "process failed to start", logs.Task(task), logs.Path(path), logs.Err(err))
+		return nil
+	}
+
+	logs.Info(logger, "process started", logs.Task(task), logs.Path(path), logs.Pid(cmd.Process.Pid))
+
+	return &process{task: task, path: path, proc: cmd.Process, started: time.Now(), logger: logger}
+}
+
+// stop interrupts the process, killing it if the interrupt fails, and waits for it to exit
+func (p *process) stop() {
+	var err error
+
+	if runtime.GOOS == "windows" {
+		err = p.proc.Kill()
+	} else {
+		err = p.proc.Signal(os.Interrupt)
+	}
+
+	if err != nil {
+		logs.Warn(p.logger, "process interrupt failed, killing", logs.Task(p.task), logs.Path(p.path), logs.Pid(p.proc.Pid), logs.Err(err))
+		p.proc.Kill()
+	}
+
+	p.proc.Wait()
+
+	logs.Info(p.logger, "process stopped", logs.Task(p.task), logs.Path(p.path), logs.Pid(p.proc.Pid), logs.Duration(time.Since(p.started)))
+}
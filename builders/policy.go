@@ -0,0 +1,183 @@
+package builders
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+
+	"github.com/influx6/flux"
+	"github.com/microcosm-cc/bluemonday"
+)
+
+// AttrRule allows attributes on elements
+type AttrRule struct {
+	Attrs    []string // Attrs are the names of the allowed attributes
+	Elements []string // Optional: elements the attributes are allowed on, allowed on every element if empty
+	Matching string   // Optional: regular expression the attribute values must match
+}
+
+// PolicyConfig builds a bluemonday sanitizing policy by allowing elements, attributes and url schemes on top of a
+// base policy, the config can be loaded from a yaml, toml or json file so each doc set can use its own rules
+type PolicyConfig struct {
+	Base           string     // Optional: ugc, strict or none, defaults to ugc. none starts from an empty policy
+	File           string     // Optional: yaml, toml or json file holding a PolicyConfig the other fields are added to
+	Elements       []string   // Optional: elements allowed without attributes eg. iframe or figure
+	Attributes     []AttrRule // Optional: attributes allowed on elements eg. src on iframe
+	URLSchemes     []string   // Optional: url schemes allowed in links eg. mailto or ftp
+	DataAttributes bool       // Optional: if true data- attributes are allowed on every element
+	Styling        bool       // Optional: if true class attributes and style attributes limited to the textStyles properties are allowed on every element
+	RelativeURLs   bool       // Optional: if true relative urls are allowed, the ugc base already allows them
+}
+
+// textStyles are the css properties Styling allows in style attributes, their values are checked by the bluemonday
+// handlers of each property and every other property is dropped so layout rules such as position can not be injected
+var textStyles = []string{
+	"color", "background-color", "font-style", "font-weight", "text-align", "text-decoration", "vertical-align",
+}
+
+// LoadPolicy reads a PolicyConfig from a .yaml, .yml, .toml or .json file, the keys match the field names in any case
+func LoadPolicy(file string) (PolicyConfig, error) {
+	var config PolicyConfig
+
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return config, err
+	}
+
+	var raw map[string]interface{}
+
+	switch filepath.Ext(file) {
+	case ".yaml", ".yml":
+		raw, err = decodeYAMLMeta(data)
+	case ".toml":
+		raw, err = decodeTOMLMeta(data)
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	default:
+		return config, fmt.Errorf("policy file %q must be yaml, toml or json", file)
+	}
+
+	if err != nil {
+		return config, err
+	}
+
+	//all formats are turned into json which the config is decoded from
+	jsdata, err := json.Marshal(raw)
+
+	if err != nil {
+		return config, err
+	}
+
+	if err := json.Unmarshal(jsdata, &config); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// merge returns the config with the rules of the other config added to it
+func (p PolicyConfig) merge(o PolicyConfig) PolicyConfig {
+	if o.Base != "" {
+		p.Base = o.Base
+	}
+
+	p.File = ""
+	p.Elements = append(p.Elements, o.Elements...)
+	p.Attributes = append(p.Attributes, o.Attributes...)
+	p.URLSchemes = append(p.URLSchemes, o.URLSchemes...)
+	p.DataAttributes = p.DataAttributes || o.DataAttributes
+	p.Styling = p.Styling || o.Styling
+	p.RelativeURLs = p.RelativeURLs || o.RelativeURLs
+	return p
+}
+
+// Policy returns the bluemonday policy of the config, loading its File first if set
+func (p PolicyConfig) Policy() (*bluemonday.Policy, error) {
+	if p.File != "" {
+		loaded, err := LoadPolicy(p.File)
+
+		if err != nil {
+			return nil, err
+		}
+
+		p = loaded.merge(p)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	var policy *bluemonday.Policy
+
+	switch p.Base {
+	case "", "ugc":
+		policy = bluemonday.UGCPolicy()
+	case "strict":
+		policy = bluemonday.StrictPolicy()
+	case "none":
+		policy = bluemonday.NewPolicy()
+	}
+
+	if len(p.Elements) > 0 {
+		policy.AllowElements(p.Elements...)
+	}
+
+	for _, rule := range p.Attributes {
+		builder := policy.AllowAttrs(rule.Attrs...)
+
+		if rule.Matching != "" {
+			builder = builder.Matching(regexp.MustCompile(rule.Matching))
+		}
+
+		if len(rule.Elements) > 0 {
+			builder.OnElements(rule.Elements...)
+		} else {
+			builder.Globally()
+		}
+	}
+
+	if len(p.URLSchemes) > 0 {
+		policy.AllowURLSchemes(p.URLSchemes...)
+	}
+
+	if p.DataAttributes {
+		policy.AllowDataAttributes()
+	}
+
+	if p.Styling {
+		policy.AllowStyling()
+		policy.AllowStyles(textStyles...).Globally()
+	}
+
+	if p.RelativeURLs {
+		policy.AllowRelativeURLs(true)
+	}
+
+	return policy, nil
+}
+
+// BlueMondayPolicy builds ontop of ByteRenderer by sanitizing with the policy of the config
+func BlueMondayPolicy(config PolicyConfig) (flux.Reactor, error) {
+	policy, err := config.Policy()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewByteRenderer(policy.SanitizeBytes)
+}
+
+// BlackMondayPolicy combines a BlackFriday and a BlueMondayPolicy to sanitize the markdown output with the policy of
+// the config
+func BlackMondayPolicy(config PolicyConfig) (flux.Reactor, error) {
+	sanitize, err := BlueMondayPolicy(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return flux.LiftOut(true, BlackFriday(), sanitize), nil
+}
@@ -0,0 +1,74 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestPolicyConfig(t *testing.T) {
+	input := []byte(`<iframe src="https://diagrams.example.com/x"></iframe><p class="note" data-id="1">hi</p><script>x</script>`)
+
+	policy, err := PolicyConfig{}.Policy()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build the default policy: %s", err)
+	}
+
+	if out := string(policy.SanitizeBytes(input)); out != "<p>hi</p>" {
+		flux.FatalFailed(t, "Expected the default policy to act as the UGC policy: %s", out)
+	}
+
+	dir, err := ioutil.TempDir("", "reactors-policy")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "policy.yaml")
+	ioutil.WriteFile(file, []byte("elements: [iframe]\nattributes:\n  - attrs: [src]\n    elements: [iframe]\n    matching: ^https://diagrams\\.example\\.com/\ndataattributes: true\n"), 0644)
+
+	policy, err = PolicyConfig{File: file, Attributes: []AttrRule{{Attrs: []string{"class"}, Elements: []string{"p"}}}}.Policy()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build the file policy: %s", err)
+	}
+
+	out := string(policy.SanitizeBytes(input))
+
+	for _, expected := range []string{`<iframe src="https://diagrams.example.com/x">`, `class="note"`, `data-id="1"`} {
+		if !strings.Contains(out, expected) {
+			flux.FatalFailed(t, "Expected %q to be kept: %s", expected, out)
+		}
+	}
+
+	if strings.Contains(out, "script") {
+		flux.FatalFailed(t, "Expected scripts to be stripped: %s", out)
+	}
+
+	policy, _ = PolicyConfig{Styling: true}.Policy()
+
+	styled := string(policy.SanitizeBytes([]byte(`<p class="note" style="position:fixed;top:0;color:red">hi</p>`)))
+
+	if strings.Contains(styled, "position") || strings.Contains(styled, "top") || !strings.Contains(styled, "color: red") || !strings.Contains(styled, `class="note"`) {
+		flux.FatalFailed(t, "Expected only the text styles to be kept: %s", styled)
+	}
+
+	policy, _ = PolicyConfig{Base: "strict"}.Policy()
+
+	if out := string(policy.SanitizeBytes([]byte("<p><b>hi</b></p>"))); out != "hi" {
+		flux.FatalFailed(t, "Expected the strict policy to strip all elements: %s", out)
+	}
+
+	if err := (PolicyConfig{Base: "loose", Attributes: []AttrRule{{Matching: "("}}}).Validate(); err == nil || !err.(*ValidationError).Has("Base") || !err.(*ValidationError).Has("Attributes[0].Attrs") || !err.(*ValidationError).Has("Attributes[0].Matching") {
+		flux.FatalFailed(t, "Expected the invalid policy to fail validation: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully built sanitizing policies")
+}
@@ -0,0 +1,346 @@
+package builders
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+)
+
+// Duration is a time.Duration which config files give as a string such as 30s or 1m30s, plain numbers are read as
+// nanoseconds
+type Duration time.Duration
+
+// UnmarshalText sets the duration from a time.ParseDuration string allowing its use in config files
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+
+	*d = Duration(duration)
+	return nil
+}
+
+// UnmarshalJSON sets the duration from a json string through UnmarshalText or from a number of nanoseconds
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var text string
+
+	if err := json.Unmarshal(data, &text); err == nil {
+		return d.UnmarshalText([]byte(text))
+	}
+
+	var nanos int64
+
+	if err := json.Unmarshal(data, &nanos); err != nil {
+		return fmt.Errorf("invalid duration %s", data)
+	}
+
+	*d = Duration(nanos)
+	return nil
+}
+
+// ProxyConfig provides the configuration for the proxy of a BinaryBuildProxy
+type ProxyConfig struct {
+	Addr    string   // Addr is the address the proxy listens on eg. :8080
+	Target  string   // Target is the url of the launched binary eg. http://localhost:3000
+	Timeout Duration // Optional: how long requests are held during a rebuild and restart eg. 10s, defaults to 30s
+
+	Logger logs.Logger // Optional: Logger receives the proxy status messages, defaults to the global logs logger
+}
+
+// proxyGate holds requests while a build and restart is in progress. Every hold starts a new generation and only the
+// build of the latest generation can release the gate, so an older build finishing late does not let requests through
+// to a binary which is about to be replaced
+type proxyGate struct {
+	rw      sync.RWMutex
+	ready   chan struct{}
+	err     error
+	gen     uint64
+	pending []proxyHold
+}
+
+// proxyHold is a build waiting to finish along with the correlation ID of its signal, wrapped is set when the proxy
+// generated the ID itself so the reply can be unwrapped again
+type proxyHold struct {
+	id      string
+	gen     uint64
+	wrapped bool
+}
+
+// newProxyGate returns a released gate so requests made before the first signal reach the target directly
+func newProxyGate() *proxyGate {
+	ready := make(chan struct{})
+	close(ready)
+	return &proxyGate{ready: ready}
+}
+
+// hold makes new requests wait until the gate is released or failed, returning the generation of the build of the
+// correlation ID
+func (p *proxyGate) hold(id string, wrapped bool) uint64 {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	select {
+	case <-p.ready:
+		p.ready = make(chan struct{})
+	default:
+		//already holding
+	}
+
+	p.gen++
+	p.pending = append(p.pending, proxyHold{id: id, gen: p.gen, wrapped: wrapped})
+
+	return p.gen
+}
+
+// done returns the pending build of the correlation ID, dropping it and the builds before it as the builds finish
+// in order. It returns false if no pending build matches eg. when the binary restarts by itself
+func (p *proxyGate) done(id string) (proxyHold, bool) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if id == "" {
+		return proxyHold{}, false
+	}
+
+	for index, hold := range p.pending {
+		if hold.id == id {
+			p.pending = p.pending[index+1:]
+			return hold, true
+		}
+	}
+
+	return proxyHold{}, false
+}
+
+// failAll drops every pending build and returns the generation of the latest one, for build errors which carry no
+// correlation ID so the failing build cannot be told apart. It returns false if no build is pending
+func (p *proxyGate) failAll() (uint64, bool) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if len(p.pending) == 0 {
+		return 0, false
+	}
+
+	gen := p.pending[len(p.pending)-1].gen
+	p.pending = nil
+
+	return gen, true
+}
+
+// release sets the build error if any and lets all held requests through if the generation is the latest one,
+// returning false if a newer build holds the gate
+func (p *proxyGate) release(gen uint64, err error) bool {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	if gen != p.gen {
+		return false
+	}
+
+	p.err = err
+
+	select {
+	case <-p.ready:
+	default:
+		close(p.ready)
+	}
+
+	return true
+}
+
+// wait blocks until the gate is released, the request is cancelled or the timeout passes, returning the build error
+func (p *proxyGate) wait(r *http.Request, timeout time.Duration) error {
+	p.rw.RLock()
+	ready := p.ready
+	p.rw.RUnlock()
+
+	select {
+	case <-ready:
+	case <-r.Context().Done():
+		return r.Context().Err()
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out after %s waiting for build and restart", timeout)
+	}
+
+	p.rw.RLock()
+	defer p.rw.RUnlock()
+	return p.err
+}
+
+// newProxyHandler returns the handler which waits on the gate before proxying to the target or rendering the build error
+func newProxyHandler(target *url.URL, gate *proxyGate, timeout time.Duration) http.Handler {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	//every request dials the target again, so a binary which is slow to listen is reached once it is up
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		writeProxyError(w, "Target unreachable", err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := gate.wait(r, timeout); err != nil {
+			writeProxyError(w, "Build failed", err)
+			return
+		}
+
+		proxy.ServeHTTP(w, r)
+	})
+}
+
+// writeProxyError renders the error as a bad gateway page
+func writeProxyError(w http.ResponseWriter, title string, err error) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusBadGateway)
+	fmt.Fprintf(w, "<!doctype html>\n<html>\n<head><meta charset=\"utf-8\"><title>%s</title></head>\n<body>\n%s\n</body>\n</html>\n", title, errorOverlay(err).HTML)
+}
+
+// waitForTarget dials the target host until it accepts connections or the timeout passes, returning false if the
+// target never accepted a connection
+func waitForTarget(host string, timeout time.Duration) bool {
+	end := time.Now().Add(timeout)
+
+	for time.Now().Before(end) {
+		if conn, err := net.DialTimeout("tcp", host, 500*time.Millisecond); err == nil {
+			conn.Close()
+			return true
+		}
+		<-time.After(100 * time.Millisecond)
+	}
+
+	return false
+}
+
+// BinaryBuildProxy fronts the binary launched by a BinaryBuildLauncher with a reverse proxy listening on the
+// proxy Addr. Every signal holds incoming requests until the rebuilt binary accepts connections on the proxy Target,
+// after which the held requests are passed on, if the build fails the requests get a page rendering the error. A binary
+// which does not accept connections within the proxy Timeout still releases the requests, each of them dialing the
+// target again and getting an error page only while it stays unreachable. Only the latest build releases the held
+// requests
+func BinaryBuildProxy(cmd BinaryBuildConfig, proxy ProxyConfig) flux.Reactor {
+	return mustReactor(NewBinaryBuildProxy(cmd, proxy))
+}
+
+// NewBinaryBuildProxy returns a BinaryBuildProxy task or a *ValidationError if either config is invalid
+func NewBinaryBuildProxy(cmd BinaryBuildConfig, proxy ProxyConfig) (flux.Reactor, error) {
+	if err := proxy.Validate(); err != nil {
+		return nil, err
+	}
+
+	launcher, err := NewBinaryBuildLauncher(cmd)
+
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := url.Parse(proxy.Target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	timeout := time.Duration(proxy.Timeout)
+
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	gate := newProxyGate()
+
+	stack := flux.ReactorStack()
+
+	//hold requests as soon as a rebuild is signaled
+	stack.React(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		id := fs.IDOf(data)
+		wrapped := id == ""
+
+		//the correlation ID ties the build back to its hold
+		if wrapped {
+			id = fs.NewID()
+			data = &fs.Envelope{ID: id, Data: fs.Unwrap(data)}
+		}
+
+		gate.hold(id, wrapped)
+		root.Reply(data)
+	}), true)
+
+	stack.Bind(launcher, true)
+
+	//release requests once the binary is up or show the build error
+	stack.Bind(flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			var gen uint64
+			var ok bool
+
+			if hold, found := gate.done(fs.IDOf(err)); found {
+				gen, ok = hold.gen, true
+			} else {
+				gen, ok = gate.failAll()
+			}
+
+			if ok && gate.release(gen, Diagnose(cmd.Name, err)) {
+				logs.Warn(proxy.Logger, "serving build error to held requests", logs.Task("BinaryBuildProxy"), logs.Err(err))
+			}
+			root.ReplyError(err)
+			return
+		}
+
+		hold, ok := gate.done(fs.IDOf(data))
+
+		if !ok {
+			root.Reply(data)
+			return
+		}
+
+		//signals without an ID of their own get their replies back unwrapped
+		if hold.wrapped {
+			data = fs.Unwrap(data)
+		}
+
+		flux.GoDefer("BinaryBuildProxy.Ready", func() {
+			start := time.Now()
+
+			//a target which is not up yet does not fail the gate, released requests dial it again themselves
+			if !waitForTarget(target.Host, timeout) {
+				if gate.release(hold.gen, nil) {
+					logs.Warn(proxy.Logger, "releasing held requests before the target accepts connections", logs.Task("BinaryBuildProxy"), logs.F("target", proxy.Target), logs.Duration(time.Since(start)))
+				}
+				return
+			}
+
+			if gate.release(hold.gen, nil) {
+				logs.Info(proxy.Logger, "releasing held requests", logs.Task("BinaryBuildProxy"), logs.F("target", proxy.Target), logs.Duration(time.Since(start)))
+			}
+		})
+
+		root.Reply(data)
+	}), true)
+
+	server := &http.Server{Addr: proxy.Addr, Handler: newProxyHandler(target, gate, timeout)}
+
+	flux.GoDefer("BinaryBuildProxy", func() {
+		logs.Info(proxy.Logger, "proxy listening", logs.Task("BinaryBuildProxy"), logs.F("addr", proxy.Addr), logs.F("target", proxy.Target))
+
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logs.Error(proxy.Logger, "proxy failed", logs.Task("BinaryBuildProxy"), logs.F("addr", proxy.Addr), logs.Err(err))
+			stack.ReplyError(err)
+		}
+	})
+
+	flux.GoDefer("BinaryBuildProxy.Close", func() {
+		<-stack.CloseNotify()
+		server.Close()
+	})
+
+	return stack, nil
+}
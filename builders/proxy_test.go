@@ -0,0 +1,182 @@
+package builders
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestProxyHoldsRequests(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("app"))
+	}))
+	defer app.Close()
+
+	target, _ := url.Parse(app.URL)
+	gate := newProxyGate()
+	handler := newProxyHandler(target, gate, 5*time.Second)
+	gen := gate.hold("a", false)
+
+	done := make(chan string)
+
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+		done <- rec.Body.String()
+	}()
+
+	select {
+	case <-done:
+		flux.FatalFailed(t, "Expected request to be held until release")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if !gate.release(gen, nil) {
+		flux.FatalFailed(t, "Expected the latest build to release the gate")
+	}
+
+	if body := <-done; body != "app" {
+		flux.FatalFailed(t, "Expected proxied response: %s", body)
+	}
+
+	flux.LogPassed(t, "Successfully held and replayed request")
+}
+
+func TestProxyBuildError(t *testing.T) {
+	target, _ := url.Parse("http://localhost:1")
+	gate := newProxyGate()
+	handler := newProxyHandler(target, gate, 5*time.Second)
+
+	gate.release(gate.hold("a", false), Diagnose("app", errors.New("main.go:3:1: undefined: app")))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "undefined: app") {
+		flux.FatalFailed(t, "Expected build error page: %d %s", rec.Code, rec.Body.String())
+	}
+
+	flux.LogPassed(t, "Successfully rendered build error page")
+}
+
+func TestProxyGateGenerations(t *testing.T) {
+	gate := newProxyGate()
+
+	gate.hold("a", false)
+	gate.hold("b", true)
+
+	old, ok := gate.done("a")
+
+	if !ok || gate.release(old.gen, nil) {
+		flux.FatalFailed(t, "Expected an older build to not release the gate")
+	}
+
+	select {
+	case <-gate.ready:
+		flux.FatalFailed(t, "Expected requests to be held for the newer build")
+	default:
+	}
+
+	if _, ok := gate.done("a"); ok {
+		flux.FatalFailed(t, "Expected a finished build to no longer be pending")
+	}
+
+	latest, ok := gate.done("b")
+
+	if !ok || !latest.wrapped || !gate.release(latest.gen, nil) {
+		flux.FatalFailed(t, "Expected the latest build to release the gate")
+	}
+
+	flux.LogPassed(t, "Successfully released the gate with the latest build only")
+}
+
+func TestProxyGateFailAll(t *testing.T) {
+	gate := newProxyGate()
+
+	select {
+	case <-gate.ready:
+	default:
+		flux.FatalFailed(t, "Expected the gate to start released")
+	}
+
+	gate.hold("a", false)
+	gate.hold("b", false)
+
+	gen, ok := gate.failAll()
+
+	if !ok || !gate.release(gen, errors.New("build failed")) {
+		flux.FatalFailed(t, "Expected an error without an ID to fail the latest build")
+	}
+
+	if _, ok := gate.failAll(); ok {
+		flux.FatalFailed(t, "Expected no build to be pending after failing all")
+	}
+
+	flux.LogPassed(t, "Successfully failed all pending builds")
+}
+
+func TestProxyUnreachableTarget(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("app"))
+	}))
+	target, _ := url.Parse(app.URL)
+	app.Close()
+
+	gate := newProxyGate()
+	handler := newProxyHandler(target, gate, 5*time.Second)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusBadGateway || !strings.Contains(rec.Body.String(), "Target unreachable") {
+		flux.FatalFailed(t, "Expected unreachable target page: %d %s", rec.Code, rec.Body.String())
+	}
+
+	if gate.err != nil {
+		flux.FatalFailed(t, "Expected an unreachable target to not fail the gate: %s", gate.err)
+	}
+
+	flux.LogPassed(t, "Successfully rendered the unreachable target page without failing the gate")
+}
+
+func TestDurationUnmarshal(t *testing.T) {
+	var config ProxyConfig
+
+	if err := json.Unmarshal([]byte(`{"Timeout": "30s"}`), &config); err != nil || time.Duration(config.Timeout) != 30*time.Second {
+		flux.FatalFailed(t, "Expected a duration string to decode: %s %s", err, time.Duration(config.Timeout))
+	}
+
+	if err := json.Unmarshal([]byte(`{"Timeout": 1000}`), &config); err != nil || time.Duration(config.Timeout) != time.Microsecond {
+		flux.FatalFailed(t, "Expected a number to decode as nanoseconds: %s %s", err, time.Duration(config.Timeout))
+	}
+
+	if err := json.Unmarshal([]byte(`{"Timeout": "soon"}`), &config); err == nil {
+		flux.FatalFailed(t, "Expected an invalid duration to fail")
+	}
+
+	flux.LogPassed(t, "Successfully decoded durations")
+}
+
+func TestWaitForTarget(t *testing.T) {
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	host := app.Listener.Addr().String()
+
+	if !waitForTarget(host, time.Second) {
+		flux.FatalFailed(t, "Expected the listening target to be reached")
+	}
+
+	app.Close()
+
+	if waitForTarget(host, 300*time.Millisecond) {
+		flux.FatalFailed(t, "Expected a closed target to not be reached")
+	}
+
+	flux.LogPassed(t, "Successfully waited for the target")
+}
@@ -0,0 +1,506 @@
+package builders
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+)
+
+// TaskFactory creates a task from its config, the config is always a pointer to a value of the type registered
+// as the Task Config or nil if the task takes no config
+type TaskFactory func(config interface{}) (flux.Reactor, error)
+
+// Task describes a task which can be created by name from the registry
+type Task struct {
+	Name        string
+	Description string
+	Config      interface{} // Optional: zero value of the config struct eg. BuildConfig{}, nil if the task takes no config
+	Required    []string    // Optional: names of the Config fields which must be set
+	Paths       []string    // Optional: names of the Config fields holding file paths, dotted for nested fields eg. Layout.TemplateDir
+	Factory     TaskFactory
+}
+
+// FieldInfo describes a configurable field of a task config
+type FieldInfo struct {
+	Name     string
+	Type     string
+	Required bool
+}
+
+// ErrTaskExists is returned when registering a task with a name already in use
+var ErrTaskExists = errors.New("task with the given name is already registered")
+
+// ErrInvalidTask is returned when registering a task without a name or factory
+var ErrInvalidTask = errors.New("task requires a Name and a Factory")
+
+var registry = struct {
+	rw    sync.RWMutex
+	tasks map[string]Task
+}{tasks: make(map[string]Task)}
+
+// RegisterTask adds the task to the registry, returning ErrTaskExists if the name is taken
+func RegisterTask(task Task) error {
+	if task.Name == "" || task.Factory == nil {
+		return ErrInvalidTask
+	}
+
+	if task.Config != nil && reflect.TypeOf(task.Config).Kind() != reflect.Struct {
+		return fmt.Errorf("task %q config must be a struct value", task.Name)
+	}
+
+	registry.rw.Lock()
+	defer registry.rw.Unlock()
+
+	if _, ok := registry.tasks[task.Name]; ok {
+		return ErrTaskExists
+	}
+
+	registry.tasks[task.Name] = task
+	return nil
+}
+
+// LookupTask returns the registered task of the given name
+func LookupTask(name string) (Task, bool) {
+	registry.rw.RLock()
+	defer registry.rw.RUnlock()
+
+	task, ok := registry.tasks[name]
+	return task, ok
+}
+
+// Tasks returns all registered tasks sorted by name
+func Tasks() []Task {
+	registry.rw.RLock()
+	defer registry.rw.RUnlock()
+
+	var tasks []Task
+
+	for _, task := range registry.tasks {
+		tasks = append(tasks, task)
+	}
+
+	sort.Sort(tasksByName(tasks))
+	return tasks
+}
+
+type tasksByName []Task
+
+func (t tasksByName) Len() int           { return len(t) }
+func (t tasksByName) Less(i, j int) bool { return t[i].Name < t[j].Name }
+func (t tasksByName) Swap(i, j int)      { t[i], t[j] = t[j], t[i] }
+
+// NewConfig returns a pointer to a new zero value of the task config or nil if the task takes no config
+func (t Task) NewConfig() interface{} {
+	if t.Config == nil {
+		return nil
+	}
+	return reflect.New(reflect.TypeOf(t.Config)).Interface()
+}
+
+// Fields returns the configurable fields of the task config, function and interface fields such as validators and
+// loggers and fields tagged json:"-" are skipped as they can not be set from config files
+func (t Task) Fields() []FieldInfo {
+	if t.Config == nil {
+		return nil
+	}
+
+	var required = make(map[string]bool)
+
+	for _, name := range t.Required {
+		required[name] = true
+	}
+
+	var fields []FieldInfo
+	ctype := reflect.TypeOf(t.Config)
+
+	for i := 0; i < ctype.NumField(); i++ {
+		field := ctype.Field(i)
+
+		if field.PkgPath != "" || field.Type.Kind() == reflect.Func || field.Type.Kind() == reflect.Interface || field.Tag.Get("json") == "-" {
+			continue
+		}
+
+		fields = append(fields, FieldInfo{
+			Name:     field.Name,
+			Type:     field.Type.String(),
+			Required: required[field.Name],
+		})
+	}
+
+	return fields
+}
+
+// Validate checks the config, a pointer as returned by NewConfig, has all the Required fields set and passes the
+// Validate method of the config if it has one
+func (t Task) Validate(config interface{}) error {
+	if t.Config == nil {
+		return nil
+	}
+
+	value := reflect.ValueOf(config)
+
+	if value.Kind() != reflect.Ptr || value.Elem().Type() != reflect.TypeOf(t.Config) {
+		return fmt.Errorf("task %q expects a *%s config", t.Name, reflect.TypeOf(t.Config))
+	}
+
+	var missing []string
+
+	for _, name := range t.Required {
+		if field := value.Elem().FieldByName(name); !field.IsValid() || isZero(field) {
+			missing = append(missing, name)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("task %q requires the config fields: %s", t.Name, strings.Join(missing, ", "))
+	}
+
+	//configs such as BuildConfig provide their own validation
+	if validator, ok := value.Elem().Interface().(interface {
+		Validate() error
+	}); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
+
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// Create validates the config and returns the task reactor from the factory
+func (t Task) Create(config interface{}) (flux.Reactor, error) {
+	if err := t.Validate(config); err != nil {
+		return nil, err
+	}
+	return t.Factory(config)
+}
+
+// Help returns a description of the task and its config fields
+func (t Task) Help() string {
+	lines := []string{fmt.Sprintf("%s: %s", t.Name, t.Description)}
+
+	for _, field := range t.Fields() {
+		line := fmt.Sprintf("    %s %s", field.Name, field.Type)
+		if field.Required {
+			line += " (required)"
+		}
+		lines = append(lines, line)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// PathConfig is the config for tasks which only take a path eg. GoInstallerWith
+type PathConfig struct {
+	Path string
+}
+
+// CommandsConfig is the config for the CommandLauncher task
+type CommandsConfig struct {
+	Commands []string
+}
+
+// BinaryConfig is the config for the BinaryLauncher and GoFileLauncher tasks
+type BinaryConfig struct {
+	Path string
+	Args []string
+}
+
+// BinaryBuildProxyConfig is the config for the BinaryBuildProxy task
+type BinaryBuildProxyConfig struct {
+	Build BinaryBuildConfig
+	Proxy ProxyConfig
+}
+
+func init() {
+	for _, task := range []Task{
+		{
+			Name:        "Watch",
+			Description: "watches a file or directory and sends down the changes",
+			Config:      fs.WatchConfig{},
+			Required:    []string{"Path"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return fs.Watch(*config.(*fs.WatchConfig)), nil
+			},
+		},
+		{
+			Name:        "WatchSet",
+			Description: "watches a set of files and directories and sends down the changes",
+			Config:      fs.WatchSetConfig{},
+			Required:    []string{"Path"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return fs.WatchSet(*config.(*fs.WatchSetConfig)), nil
+			},
+		},
+		{
+			Name:        "PackageWatcher",
+			Description: "watches a go package and its dependencies and sends down the changes",
+			Config:      PackageWatchConfig{},
+			Required:    []string{"Package"},
+			Paths:       []string{"Dir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return PackageWatcherWith(*config.(*PackageWatchConfig))
+			},
+		},
+		{
+			Name:        "GitChanges",
+			Description: "sends the files changed in a git repository since a ref on every signal",
+			Config:      GitChangeConfig{},
+			Required:    []string{"Path", "Ref"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return GitChanges(*config.(*GitChangeConfig))
+			},
+		},
+		{
+			Name:        "FileReader",
+			Description: "reads the file paths it receives and sends down a *fs.FileRead",
+			Factory: func(_ interface{}) (flux.Reactor, error) {
+				return fs.FileReader(), nil
+			},
+		},
+		{
+			Name:        "FileWriter",
+			Description: "writes the *fs.FileWrite it receives",
+			Factory: func(_ interface{}) (flux.Reactor, error) {
+				return fs.FileWriter(nil), nil
+			},
+		},
+		{
+			Name:        "FileRemover",
+			Description: "removes the *fs.RemoveFile paths it receives",
+			Factory: func(_ interface{}) (flux.Reactor, error) {
+				return fs.FileRemover(), nil
+			},
+		},
+		{
+			Name:        "GoInstallerWith",
+			Description: "calls go get on the path on every signal",
+			Config:      PathConfig{},
+			Required:    []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return GoInstallerWith(config.(*PathConfig).Path), nil
+			},
+		},
+		{
+			Name:        "GoBuilderWith",
+			Description: "calls go build with the config on every signal",
+			Config:      BuildConfig{},
+			Required:    []string{"Path", "Name"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewGoBuilderWith(*config.(*BuildConfig))
+			},
+		},
+		{
+			Name:        "CommandLauncher",
+			Description: "runs the list of commands on every signal",
+			Config:      CommandsConfig{},
+			Required:    []string{"Commands"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewCommandLauncher(config.(*CommandsConfig).Commands)
+			},
+		},
+		{
+			Name:        "BinaryLauncher",
+			Description: "relaunches the binary at the path when it receives true and stops it on false",
+			Config:      BinaryConfig{},
+			Required:    []string{"Path"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				bin := config.(*BinaryConfig)
+				return BinaryLauncher(bin.Path, bin.Args), nil
+			},
+		},
+		{
+			Name:        "GoFileLauncher",
+			Description: "relaunches go run for the file at the path on every signal",
+			Config:      BinaryConfig{},
+			Required:    []string{"Path"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				bin := config.(*BinaryConfig)
+				return GoFileLauncher(bin.Path, bin.Args), nil
+			},
+		},
+		{
+			Name:        "BinaryBuildLauncher",
+			Description: "rebuilds and relaunches a go binary on every signal",
+			Config:      BinaryBuildConfig{},
+			Required:    []string{"Path", "Name"},
+			Paths:       []string{"Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewBinaryBuildLauncher(*config.(*BinaryBuildConfig))
+			},
+		},
+		{
+			Name:        "BinaryBuildProxy",
+			Description: "rebuilds and relaunches a go binary on every signal behind a reverse proxy holding requests during restarts",
+			Config:      BinaryBuildProxyConfig{},
+			Required:    []string{"Build", "Proxy"},
+			Paths:       []string{"Build.Path"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				proxy := config.(*BinaryBuildProxyConfig)
+				return NewBinaryBuildProxy(proxy.Build, proxy.Proxy)
+			},
+		},
+		{
+			Name:        "JSBuildLauncher",
+			Description: "builds a gopherjs package on every signal and replies the js, manifest, size report and budget warnings without writing them",
+			Config:      JSBuildConfig{},
+			Required:    []string{"Package"},
+			Paths:       []string{"Folder"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewJSBuildLauncher(*config.(*JSBuildConfig))
+			},
+		},
+		{
+			Name:        "JSLauncher",
+			Description: "builds a gopherjs package and writes the js files on every signal",
+			Config:      JSBuildConfig{},
+			Required:    []string{"Package"},
+			Paths:       []string{"Folder"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewJSLauncher(*config.(*JSBuildConfig))
+			},
+		},
+		{
+			Name:        "JSMultiLauncher",
+			Description: "builds several gopherjs entries with a shared runtime and writes the js files on every signal",
+			Config:      JSMultiBuildConfig{},
+			Required:    []string{"Entries"},
+			Paths:       []string{"Folder"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewJSMultiLauncher(*config.(*JSMultiBuildConfig))
+			},
+		},
+		{
+			Name:        "JSMultiBuildLauncher",
+			Description: "builds several gopherjs entries with a shared runtime on every signal and replies the js files and manifest without writing them",
+			Config:      JSMultiBuildConfig{},
+			Required:    []string{"Entries"},
+			Paths:       []string{"Folder"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewJSMultiBuildLauncher(*config.(*JSMultiBuildConfig))
+			},
+		},
+		{
+			Name:        "DevServer",
+			Description: "serves a directory with live-reload for the file writes and errors it receives",
+			Config:      DevServerConfig{},
+			Required:    []string{"Addr"},
+			Paths:       []string{"Dir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewDevServer(*config.(*DevServerConfig))
+			},
+		},
+		{
+			Name:        "BlackFriday",
+			Description: "renders the markdown of the *RenderFile it receives",
+			Factory: func(_ interface{}) (flux.Reactor, error) {
+				return BlackFriday(), nil
+			},
+		},
+		{
+			Name:        "BlackMonday",
+			Description: "renders and sanitizes the markdown of the *RenderFile it receives",
+			Config:      PolicyConfig{},
+			Paths:       []string{"File"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return BlackMondayPolicy(*config.(*PolicyConfig))
+			},
+		},
+		{
+			Name:        "Markdown",
+			Description: "renders the markdown of the *RenderFile it receives with the selected engine",
+			Config:      MarkdownOptions{},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return Markdown(*config.(*MarkdownOptions))
+			},
+		},
+		{
+			Name:        "Highlight",
+			Description: "highlights the fenced code blocks of the rendered *RenderFile it receives",
+			Config:      HighlightConfig{},
+			Paths:       []string{"CSSFile"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return Highlight(*config.(*HighlightConfig))
+			},
+		},
+		{
+			Name:        "TOC",
+			Description: "adds heading ids, anchors and the table of contents to the rendered *RenderFile it receives",
+			Config:      TOCConfig{},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return TOC(*config.(*TOCConfig))
+			},
+		},
+		{
+			Name:        "Layout",
+			Description: "executes the layout template of each *RenderFile it receives into a full page",
+			Config:      LayoutConfig{},
+			Required:    []string{"TemplateDir"},
+			Paths:       []string{"TemplateDir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return Layout(*config.(*LayoutConfig))
+			},
+		},
+		{
+			Name:        "Site",
+			Description: "builds a static site with index pages, a sitemap and a feed from a markdown directory on every signal",
+			Config:      SiteConfig{},
+			Required:    []string{"InputDir", "SaveDir"},
+			Paths:       []string{"InputDir", "SaveDir", "StateFile", "Policy.File", "Layout.TemplateDir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return Site(*config.(*SiteConfig))
+			},
+		},
+		{
+			Name:        "MarkFriday",
+			Description: "reads the markdown file paths it receives and writes the rendered output",
+			Config:      MarkConfig{},
+			Paths:       []string{"SaveDir", "Policy.File", "Layout.TemplateDir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return NewMarkFriday(*config.(*MarkConfig))
+			},
+		},
+		{
+			Name:        "MarkFridayStream",
+			Description: "renders every markdown file in a directory into the save directory on every signal",
+			Config:      MarkStreamConfig{},
+			Required:    []string{"InputDir"},
+			Paths:       []string{"InputDir", "SaveDir", "StateFile", "Policy.File", "Layout.TemplateDir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return MarkFridayStream(*config.(*MarkStreamConfig))
+			},
+		},
+		{
+			Name:        "GoFridayStream",
+			Description: "renders every markdown file in a directory into go templates on every signal",
+			Config:      MarkStreamConfig{},
+			Required:    []string{"InputDir"},
+			Paths:       []string{"InputDir", "SaveDir", "StateFile", "Policy.File", "Layout.TemplateDir"},
+			Factory: func(config interface{}) (flux.Reactor, error) {
+				return GoFridayStream(*config.(*MarkStreamConfig))
+			},
+		},
+	} {
+		if err := RegisterTask(task); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package builders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestRegistryLookup(t *testing.T) {
+	task, ok := LookupTask("GoBuilderWith")
+
+	if !ok {
+		flux.FatalFailed(t, "Expected GoBuilderWith to be registered")
+	}
+
+	config := task.NewConfig()
+
+	if _, ok := config.(*BuildConfig); !ok {
+		flux.FatalFailed(t, "Expected *BuildConfig from NewConfig: %+s", config)
+	}
+
+	if err := task.Validate(config); err == nil || !strings.Contains(err.Error(), "Path, Name") {
+		flux.FatalFailed(t, "Expected missing Path and Name fields: %s", err)
+	}
+
+	if help := task.Help(); !strings.Contains(help, "Path string (required)") {
+		flux.FatalFailed(t, "Expected required field in help: %s", help)
+	}
+
+	flux.LogPassed(t, "Successfully described task: %s", task.Help())
+}
+
+func TestRegistryRegister(t *testing.T) {
+	err := RegisterTask(Task{
+		Name:    "FileReader",
+		Factory: func(_ interface{}) (flux.Reactor, error) { return nil, nil },
+	})
+
+	if err != ErrTaskExists {
+		flux.FatalFailed(t, "Expected ErrTaskExists: %s", err)
+	}
+
+	if err := RegisterTask(Task{Name: "Custom"}); err != ErrInvalidTask {
+		flux.FatalFailed(t, "Expected ErrInvalidTask: %s", err)
+	}
+
+	for _, task := range []string{"Watch", "WatchSet", "FileWriter", "JSLauncher", "JSBuildLauncher", "JSMultiBuildLauncher", "BinaryBuildProxy", "MarkFriday"} {
+		if _, ok := LookupTask(task); !ok {
+			flux.FatalFailed(t, "Expected %s to be registered", task)
+		}
+	}
+
+	flux.LogPassed(t, "Successfully registered %d tasks", len(Tasks()))
+}
@@ -0,0 +1,751 @@
+package builders
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html/template"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/influx6/assets"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+	"github.com/influx6/reactors/logs"
+)
+
+// SiteConfig provides the configuration for the Site task
+type SiteConfig struct {
+	InputDir    string               // InputDir holds the markdown files of the site
+	SaveDir     string               // SaveDir receives the pages, the index pages, the sitemap and the feed
+	BaseURL     string               // Optional: absolute url of the site used by the sitemap and feed eg. https://example.com
+	Title       string               // Optional: title of the site used by the index pages and the feed
+	PageSize    int                  // Optional: number of pages listed per index page, defaults to 10
+	Feed        string               // Optional: rss, atom or none, defaults to rss
+	Sanitize    bool                 // Optional: if true the rendered markdown is sanitized with bluemonday
+	Policy      PolicyConfig         // Optional: sanitizing policy used when Sanitize is set, defaults to the bluemonday UGCPolicy
+	Markdown    MarkdownOptions      // Optional: selects the markdown engine and its extensions, defaults to blackfriday
+	Highlight   HighlightConfig      // Optional: if Enabled the fenced code blocks are highlighted after sanitizing, see Highlight
+	TOC         TOCConfig            // Optional: if Enabled the headings get ids and anchors and the pages their TOC, see TOC
+	Links       LinkConfig           // Optional: if Rewrite is set the links between markdown files point to their pages, see MarkFridayStream
+	Drafts      bool                 // Optional: if true files marked with draft: true are built instead of skipped
+	Layout      LayoutConfig         // Optional: if its TemplateDir is set pages are executed with their layout and index pages with the index layout
+	Cleanup     bool                 // Optional: if true the pages of deleted sources and index pages no longer built are removed, see MarkFridayStream
+	Incremental bool                 // Optional: if true only the changed pages are rendered again, see MarkFridayStream
+	StateFile   string               // Optional: state file of Cleanup and Incremental, defaults to .markfriday.json within the SaveDir
+	Validator   assets.PathValidator // Optional: filters the files of the InputDir, defaults to the .md and .markdown files
+	Logger      logs.Logger          // Optional: Logger receives the build summaries, defaults to the global logs logger
+}
+
+// SiteIndex is the data index layouts are executed with, an index page lists one page of a collection
+type SiteIndex struct {
+	Title      string // Title of the site
+	Kind       string // Kind of the collection: site, section or tag
+	Collection string // Collection is the section or tag name, empty for the site collection
+	Permalink  string // Permalink is the url of this index page
+	Pages      []Page // Pages are the pages listed by this index page
+	PageNumber int    // PageNumber is the number of this index page starting at 1
+	TotalPages int    // TotalPages is the number of index pages of the collection
+	PrevURL    string // PrevURL is the url of the previous index page if any
+	NextURL    string // NextURL is the url of the next index page if any
+}
+
+// siteIndexLayout is used for index pages when the layouts have no index template
+var siteIndexLayout = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>{{if .Collection}}{{.Collection}} - {{end}}{{.Title}}</title></head>
+<body>
+<h1>{{if .Collection}}{{.Collection}}{{else}}{{.Title}}{{end}}</h1>
+<ul>
+{{range .Pages}}<li><a href="{{.Permalink}}">{{if .Title}}{{.Title}}{{else}}{{.Permalink}}{{end}}</a>{{if not .Date.IsZero}} <time>{{.Date.Format "2006-01-02"}}</time>{{end}}</li>
+{{end}}</ul>
+<nav>{{if .PrevURL}}<a rel="prev" href="{{.PrevURL}}">Newer</a>{{end}} {{if .NextURL}}<a rel="next" href="{{.NextURL}}">Older</a>{{end}}</nav>
+</body>
+</html>
+`))
+
+// sitePage is a rendered page of the site with the details used to place it in collections
+type sitePage struct {
+	Page
+	section string
+	tags    []string
+	landing bool
+}
+
+// siteCollection is a list of pages served under a permalink
+type siteCollection struct {
+	kind      string
+	name      string
+	permalink string
+	pages     []*sitePage
+}
+
+// Permalink returns the pretty url of the markdown file at the path relative to the site input dir, an index file
+// takes the url of its directory, a slug key replaces the file name and a permalink key replaces the whole url
+func Permalink(rel string, meta map[string]interface{}) string {
+	if link := MetaString(meta, "permalink"); link != "" {
+		if link = strings.Trim(link, "/"); link == "" {
+			return "/"
+		}
+		return "/" + link + "/"
+	}
+
+	rel = filepath.ToSlash(rel)
+	dir, base := path.Dir(rel), strings.TrimSuffix(path.Base(rel), path.Ext(rel))
+
+	if slug := MetaString(meta, "slug"); slug != "" {
+		base = slug
+	}
+
+	if base == "index" {
+		base = ""
+	}
+
+	link := path.Join("/", dir, base)
+
+	if link == "/" {
+		return link
+	}
+
+	return link + "/"
+}
+
+// slugify turns a name such as a tag or heading into a lowercase url segment of letters, digits and dashes
+func slugify(name string) string {
+	var slug []rune
+	var dash bool
+
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case unicode.IsLetter(r), unicode.IsDigit(r):
+			slug = append(slug, r)
+			dash = false
+		case !dash && len(slug) > 0:
+			slug = append(slug, '-')
+			dash = true
+		}
+	}
+
+	return strings.TrimSuffix(string(slug), "-")
+}
+
+// pagePath returns the output path of the permalink within the save dir
+func pagePath(saveDir, permalink string) string {
+	return filepath.Join(saveDir, filepath.FromSlash(permalink), "index.html")
+}
+
+// absURL joins the base url of the site with the permalink
+func absURL(base, permalink string) string {
+	return strings.TrimRight(base, "/") + permalink
+}
+
+// site builds the index pages, sitemap and feed of a SiteConfig out of the pages rendered by its MarkFridayStream,
+// which it observes to collect the pages of each run and build once all of them are rendered
+type site struct {
+	config  SiteConfig
+	input   string
+	set     *layoutSet
+	out     flux.Reactor
+	files   flux.Reactor
+	remover flux.Reactor
+	mu      sync.Mutex
+	pages   map[string]*sitePage
+	run     *siteRun
+	built   map[string]bool
+}
+
+// siteRun holds the pages a run of the stream has left to render
+type siteRun struct {
+	id      string
+	pending map[string]bool
+}
+
+func newSite(config SiteConfig) (*site, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	if config.PageSize <= 0 {
+		config.PageSize = 10
+	}
+
+	if config.Feed == "" {
+		config.Feed = "rss"
+	}
+
+	if config.Validator == nil {
+		config.Validator = func(path string, info os.FileInfo) bool {
+			ext := filepath.Ext(path)
+			return ext == ".md" || ext == ".markdown"
+		}
+	}
+
+	input, err := filepath.Abs(config.InputDir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s := &site{config: config, input: input, pages: make(map[string]*sitePage), built: make(map[string]bool)}
+
+	if config.Layout.TemplateDir != "" {
+		set, err := newLayoutSet(config.Layout)
+
+		if err != nil {
+			return nil, err
+		}
+
+		s.set = set
+	}
+
+	return s, nil
+}
+
+// streamConfig returns the config of the MarkFridayStream rendering the pages to their permalinks
+func (s *site) streamConfig() MarkStreamConfig {
+	return MarkStreamConfig{
+		InputDir:    s.config.InputDir,
+		SaveDir:     s.config.SaveDir,
+		Sanitize:    s.config.Sanitize,
+		Policy:      s.config.Policy,
+		Markdown:    s.config.Markdown,
+		Highlight:   s.config.Highlight,
+		TOC:         s.config.TOC,
+		Links:       s.config.Links,
+		Drafts:      s.config.Drafts,
+		Layout:      s.config.Layout,
+		Cleanup:     s.config.Cleanup,
+		Incremental: s.config.Incremental,
+		StateFile:   s.config.StateFile,
+		Validator:   s.config.Validator,
+		OutputPath: func(rel string, meta map[string]interface{}) string {
+			return pagePath(s.config.SaveDir, Permalink(rel, meta))
+		},
+	}
+}
+
+// missing returns the sources whose pages were not rendered yet, so an incremental stream renders every page once
+func (s *site) missing(sources map[string]bool) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var missing []string
+
+	for source := range sources {
+		if _, ok := s.pages[source]; !ok {
+			missing = append(missing, source)
+		}
+	}
+
+	return missing
+}
+
+// start drops the pages of deleted sources and waits for the pages of the run, building at once if it has none
+func (s *site) start(id string, sources, pages map[string]bool) {
+	s.mu.Lock()
+
+	for source := range s.pages {
+		if !sources[source] {
+			delete(s.pages, source)
+		}
+	}
+
+	run := &siteRun{id: id, pending: make(map[string]bool)}
+
+	for page := range pages {
+		run.pending[page] = true
+	}
+
+	s.run = run
+
+	if len(run.pending) == 0 {
+		s.run = nil
+	}
+
+	s.mu.Unlock()
+
+	if len(run.pending) == 0 {
+		s.finish(id)
+	}
+}
+
+// rendered collects the page of the rendered file, building the site once it is the last page of its run, and returns
+// the file with its permalink for the layout
+func (s *site) rendered(rf *RenderFile) *RenderFile {
+	path, _ := filepath.Abs(rf.Path)
+	rel, _ := filepath.Rel(s.input, path)
+	page := newSitePage(rf, rel)
+
+	s.mu.Lock()
+	s.pages[path] = page
+
+	run := s.run
+	done := run != nil && run.id == rf.ID
+
+	if done {
+		delete(run.pending, path)
+
+		if done = len(run.pending) == 0; done {
+			s.run = nil
+		}
+	}
+
+	s.mu.Unlock()
+
+	if done {
+		s.finish(rf.ID)
+	}
+
+	next := *rf
+	next.Permalink = page.Permalink
+	return &next
+}
+
+// newSitePage returns the page of the rendered file at the path relative to the input dir
+func newSitePage(rf *RenderFile, rel string) *sitePage {
+	meta := rf.Meta
+
+	if meta == nil {
+		meta = make(map[string]interface{})
+	}
+
+	page := &sitePage{
+		Page: Page{
+			Path:      rf.Path,
+			Content:   template.HTML(rf.Data),
+			TOC:       rf.TOC,
+			Meta:      meta,
+			Title:     MetaString(meta, "title"),
+			Date:      MetaTime(meta, "date"),
+			Permalink: Permalink(rel, meta),
+		},
+		tags:    MetaStrings(meta, "tags"),
+		landing: strings.TrimSuffix(filepath.Base(rel), filepath.Ext(rel)) == "index",
+	}
+
+	if page.Date.IsZero() {
+		if info, err := os.Stat(rf.Path); err == nil {
+			page.Date = info.ModTime()
+		}
+	}
+
+	if parts := strings.SplitN(filepath.ToSlash(rel), "/", 2); len(parts) > 1 {
+		page.section = parts[0]
+	}
+
+	return page
+}
+
+// writer returns the reactor replying the writes and removals of the index pages, sitemap and feed
+func (s *site) writer() flux.Reactor {
+	return s.out
+}
+
+// finish builds the site of the run and writes its files, removing the index pages no longer built with Cleanup set
+func (s *site) finish(id string) {
+	writes, err := s.build(id)
+
+	if err != nil {
+		s.out.SendError(err)
+		return
+	}
+
+	var built = make(map[string]bool)
+	var stale []string
+
+	for _, write := range writes {
+		built[write.Path] = true
+	}
+
+	s.mu.Lock()
+
+	for path := range s.built {
+		if !built[path] {
+			stale = append(stale, path)
+		}
+	}
+
+	s.built = built
+	pages := len(s.pages)
+	s.mu.Unlock()
+
+	for _, write := range writes {
+		write.ID = id
+		s.files.Send(write)
+	}
+
+	if s.config.Cleanup {
+		for _, path := range stale {
+			s.remover.Send(&fs.RemoveFile{Path: path, ID: id})
+		}
+	}
+
+	logs.Info(s.config.Logger, "site built", logs.Task("Site"), logs.Path(s.config.SaveDir), logs.F("pages", pages), logs.F("files", len(writes)))
+}
+
+// sorted returns the collected pages sorted from the newest
+func (s *site) sorted() []*sitePage {
+	s.mu.Lock()
+
+	var pages []*sitePage
+
+	for _, page := range s.pages {
+		pages = append(pages, page)
+	}
+
+	s.mu.Unlock()
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		if !pages[i].Date.Equal(pages[j].Date) {
+			return pages[i].Date.After(pages[j].Date)
+		}
+		return pages[i].Permalink < pages[j].Permalink
+	})
+
+	return pages
+}
+
+// collections groups the pages into the site, section and tag collections, landing pages are not listed
+func (s *site) collections(pages []*sitePage) []*siteCollection {
+	all := &siteCollection{kind: "site", permalink: "/"}
+	var sections []*siteCollection
+	var tags []*siteCollection
+	var named = make(map[string]*siteCollection)
+
+	collection := func(list *[]*siteCollection, kind, name, permalink string) *siteCollection {
+		if col, ok := named[permalink]; ok {
+			return col
+		}
+
+		col := &siteCollection{kind: kind, name: name, permalink: permalink}
+		named[permalink] = col
+		*list = append(*list, col)
+		return col
+	}
+
+	for _, page := range pages {
+		if page.landing {
+			continue
+		}
+
+		all.pages = append(all.pages, page)
+
+		if page.section != "" {
+			col := collection(&sections, "section", page.section, "/"+page.section+"/")
+			col.pages = append(col.pages, page)
+		}
+
+		for _, tag := range page.tags {
+			if slug := slugify(tag); slug != "" {
+				col := collection(&tags, "tag", tag, "/tags/"+slug+"/")
+				col.pages = append(col.pages, page)
+			}
+		}
+	}
+
+	return append(append([]*siteCollection{all}, sections...), tags...)
+}
+
+// indexes returns the index pages of the collection, skipping the first one if a landing page claims its permalink
+func (s *site) indexes(col *siteCollection, claimed map[string]bool) []SiteIndex {
+	total := (len(col.pages) + s.config.PageSize - 1) / s.config.PageSize
+
+	if total == 0 {
+		total = 1
+	}
+
+	link := func(number int) string {
+		if number == 1 {
+			return col.permalink
+		}
+		return fmt.Sprintf("%spage/%d/", col.permalink, number)
+	}
+
+	var indexes []SiteIndex
+
+	for number := 1; number <= total; number++ {
+		start := (number - 1) * s.config.PageSize
+		end := start + s.config.PageSize
+
+		if end > len(col.pages) {
+			end = len(col.pages)
+		}
+
+		index := SiteIndex{
+			Title:      s.config.Title,
+			Kind:       col.kind,
+			Collection: col.name,
+			Permalink:  link(number),
+			PageNumber: number,
+			TotalPages: total,
+		}
+
+		for _, page := range col.pages[start:end] {
+			index.Pages = append(index.Pages, page.Page)
+		}
+
+		if number > 1 {
+			index.PrevURL = link(number - 1)
+		}
+
+		if number < total {
+			index.NextURL = link(number + 1)
+		}
+
+		if !claimed[index.Permalink] {
+			indexes = append(indexes, index)
+		}
+	}
+
+	return indexes
+}
+
+// renderIndex executes the index layout if the layouts have one or the builtin index layout
+func (s *site) renderIndex(id string, index SiteIndex) ([]byte, error) {
+	if s.set != nil && s.set.has(id, "index") {
+		return s.set.execute(id, "index", index)
+	}
+
+	var buf bytes.Buffer
+
+	if err := siteIndexLayout.Execute(&buf, index); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// build returns the file writes of the index pages, sitemap and feed of the collected pages for the run of the id
+func (s *site) build(id string) ([]*fs.FileWrite, error) {
+	pages := s.sorted()
+
+	var writes []*fs.FileWrite
+	var links []string
+	var claimed = make(map[string]bool)
+
+	for _, page := range pages {
+		claimed[page.Permalink] = true
+		links = append(links, page.Permalink)
+	}
+
+	for _, col := range s.collections(pages) {
+		for _, index := range s.indexes(col, claimed) {
+			data, err := s.renderIndex(id, index)
+
+			if err != nil {
+				return nil, fmt.Errorf("index %s: %s", index.Permalink, err)
+			}
+
+			links = append(links, index.Permalink)
+			writes = append(writes, &fs.FileWrite{Path: pagePath(s.config.SaveDir, index.Permalink), Data: data})
+		}
+	}
+
+	sitemap, err := s.sitemap(pages, links)
+
+	if err != nil {
+		return nil, err
+	}
+
+	writes = append(writes, &fs.FileWrite{Path: filepath.Join(s.config.SaveDir, "sitemap.xml"), Data: sitemap})
+
+	if s.config.Feed != "none" {
+		feed, err := s.feed(pages)
+
+		if err != nil {
+			return nil, err
+		}
+
+		writes = append(writes, &fs.FileWrite{Path: filepath.Join(s.config.SaveDir, s.config.Feed+".xml"), Data: feed})
+	}
+
+	return writes, nil
+}
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// sitemap returns the sitemap.xml listing every page and index page
+func (s *site) sitemap(pages []*sitePage, links []string) ([]byte, error) {
+	var dates = make(map[string]time.Time)
+
+	for _, page := range pages {
+		dates[page.Permalink] = page.Date
+	}
+
+	set := sitemapSet{Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+
+	for _, link := range links {
+		url := sitemapURL{Loc: absURL(s.config.BaseURL, link)}
+
+		if date, ok := dates[link]; ok {
+			url.LastMod = date.Format("2006-01-02")
+		}
+
+		set.URLs = append(set.URLs, url)
+	}
+
+	return marshalXML(set)
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	GUID        string `xml:"guid"`
+	PubDate     string `xml:"pubDate"`
+	Description string `xml:"description"`
+}
+
+type rssChannel struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Description string    `xml:"description"`
+	Items       []rssItem `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomContent struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Content atomContent `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Link    atomLink    `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// feedSize is the number of the newest pages listed in the feed
+const feedSize = 20
+
+// feed returns the rss or atom feed of the newest pages
+func (s *site) feed(pages []*sitePage) ([]byte, error) {
+	var entries []*sitePage
+
+	for _, page := range pages {
+		if !page.landing && len(entries) < feedSize {
+			entries = append(entries, page)
+		}
+	}
+
+	home := absURL(s.config.BaseURL, "/")
+
+	if s.config.Feed == "atom" {
+		feed := atomFeed{Title: s.config.Title, ID: home, Link: atomLink{Href: home}}
+
+		for _, page := range entries {
+			link := absURL(s.config.BaseURL, page.Permalink)
+			feed.Entries = append(feed.Entries, atomEntry{
+				Title:   page.Title,
+				ID:      link,
+				Updated: page.Date.Format(time.RFC3339),
+				Link:    atomLink{Href: link, Rel: "alternate"},
+				Content: atomContent{Type: "html", Body: string(page.Content)},
+			})
+		}
+
+		if len(entries) > 0 {
+			feed.Updated = entries[0].Date.Format(time.RFC3339)
+		}
+
+		return marshalXML(feed)
+	}
+
+	feed := rssFeed{Version: "2.0", Channel: rssChannel{Title: s.config.Title, Link: home, Description: s.config.Title}}
+
+	for _, page := range entries {
+		link := absURL(s.config.BaseURL, page.Permalink)
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:       page.Title,
+			Link:        link,
+			GUID:        link,
+			PubDate:     page.Date.Format(time.RFC1123Z),
+			Description: string(page.Content),
+		})
+	}
+
+	return marshalXML(feed)
+}
+
+// marshalXML returns the indented xml document of the value with its header
+func marshalXML(v interface{}) ([]byte, error) {
+	data, err := xml.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+// Site returns a task which on every signal builds a static site from the markdown files of the config InputDir on top
+// of a MarkFridayStream: each page is rendered to a pretty permalink, the pages are grouped into the site collection,
+// a collection per top level directory and one per tag with paginated index pages, and a sitemap.xml and rss or atom
+// feed are generated once all the pages of a run are rendered. Link rewriting, Cleanup and Incremental behave as in
+// MarkFridayStream, an incremental Site renders every page on its first run to collect them for its index pages. The
+// *fs.FileWrite replies of the index pages, sitemap and feed are sent down along with the replies of the stream
+func Site(config SiteConfig) (flux.Reactor, error) {
+	s, err := newSite(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.out = flux.Reactive(func(root flux.Reactor, err error, data interface{}) {
+		if err != nil {
+			root.ReplyError(err)
+			return
+		}
+		root.Reply(data)
+	})
+
+	s.files = fs.FileWriter(nil)
+	s.remover = fs.FileRemover()
+
+	for _, writer := range []flux.Reactor{s.files, s.remover} {
+		writer.React(func(_ flux.Reactor, err error, data interface{}) {
+			if err != nil {
+				s.out.SendError(err)
+				return
+			}
+			s.out.Send(data)
+		}, true)
+	}
+
+	return markFridayStream(s.streamConfig(), s)
+}
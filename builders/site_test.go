@@ -0,0 +1,147 @@
+package builders
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/fs"
+)
+
+func TestPermalink(t *testing.T) {
+	cases := map[string]string{
+		"about.md":          "/about/",
+		"index.md":          "/",
+		"blog/index.md":     "/blog/",
+		"blog/hello.md":     "/blog/hello/",
+		"blog/2016/post.md": "/blog/2016/post/",
+	}
+
+	for rel, expected := range cases {
+		if link := Permalink(filepath.FromSlash(rel), nil); link != expected {
+			flux.FatalFailed(t, "Expected %q for %q but got %q", expected, rel, link)
+		}
+	}
+
+	if link := Permalink("blog/hello.md", map[string]interface{}{"slug": "hi"}); link != "/blog/hi/" {
+		flux.FatalFailed(t, "Expected the slug to replace the file name: %q", link)
+	}
+
+	if link := Permalink("blog/hello.md", map[string]interface{}{"permalink": "/custom/path"}); link != "/custom/path/" {
+		flux.FatalFailed(t, "Expected the permalink to replace the url: %q", link)
+	}
+
+	if link := Permalink("blog/hello.md", map[string]interface{}{"permalink": "/"}); link != "/" {
+		flux.FatalFailed(t, "Expected the root permalink to stay the site root: %q", link)
+	}
+
+	flux.LogPassed(t, "Successfully generated permalinks")
+}
+
+func TestSiteBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "reactors-site")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create temp dir: %s", err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"index.md":       "---\ntitle: Home\n---\nwelcome\n",
+		"about.md":       "+++\ntitle = \"About\"\ndate = 2016-01-01\n+++\nabout\n",
+		"blog/first.md":  "---\ntitle: First\ndate: 2016-02-01\ntags: [Go, Web Dev]\n---\nfirst\n",
+		"blog/second.md": "---\ntitle: Second\ndate: 2016-03-01\ntags: go\n---\nsecond\n",
+		"blog/draft.md":  "---\ntitle: Draft\ndraft: true\n---\ndraft\n",
+	}
+
+	for name, content := range files {
+		os.MkdirAll(filepath.Join(dir, "src", filepath.Dir(name)), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "src", name), []byte(content), 0644)
+	}
+
+	out := filepath.Join(dir, "out")
+
+	s, err := newSite(SiteConfig{InputDir: filepath.Join(dir, "src"), SaveDir: out, BaseURL: "https://example.com/", Title: "Site", PageSize: 2})
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create site: %s", err)
+	}
+
+	src, _ := filepath.Abs(filepath.Join(dir, "src"))
+	sources, err := newSourceCache().sources(src, s.config.Validator, false)
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to list sources: %s", err)
+	}
+
+	output := s.streamConfig().OutputPath
+	renderer, err := MarkdownOptions{}.Renderer()
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to create renderer: %s", err)
+	}
+
+	pages := make(map[string]bool)
+
+	//render stands in for the stream, handing every rendered page to the site
+	for source := range sources {
+		data, _ := ioutil.ReadFile(source)
+		meta, body := ParseFrontMatter(data)
+		rel, _ := filepath.Rel(src, source)
+		rf := s.rendered(&RenderFile{Path: source, Data: renderer(body), Meta: meta})
+
+		if page, _ := filepath.Rel(out, output(rel, meta)); filepath.ToSlash(page) != strings.TrimPrefix(rf.Permalink, "/")+"index.html" {
+			flux.FatalFailed(t, "Expected %s to be rendered to its permalink %s: %s", rel, rf.Permalink, page)
+		}
+
+		pages[filepath.ToSlash(rel)] = true
+	}
+
+	if len(pages) != 4 || pages["blog/draft.md"] {
+		flux.FatalFailed(t, "Expected no drafts to be rendered: %+v", pages)
+	}
+
+	writes, err := s.build("")
+
+	if err != nil {
+		flux.FatalFailed(t, "Unable to build site: %s", err)
+	}
+
+	var built = make(map[string]*fs.FileWrite)
+
+	for _, write := range writes {
+		rel, _ := filepath.Rel(out, write.Path)
+		built[filepath.ToSlash(rel)] = write
+	}
+
+	for _, expected := range []string{
+		"page/2/index.html", "blog/index.html", "tags/go/index.html", "tags/web-dev/index.html",
+		"sitemap.xml", "rss.xml",
+	} {
+		if built[expected] == nil {
+			flux.FatalFailed(t, "Expected %q to be built: %+s", expected, writes)
+		}
+	}
+
+	if len(built) != 6 || built["index.html"] != nil {
+		flux.FatalFailed(t, "Expected the index page to take the site index url and no extra files: %d files", len(built))
+	}
+
+	if blog := string(built["blog/index.html"].Data); !strings.Contains(blog, `href="/blog/second/"`) || strings.Index(blog, "Second") > strings.Index(blog, "First") {
+		flux.FatalFailed(t, "Expected the blog index to list the newest first: %s", blog)
+	}
+
+	if sitemap := string(built["sitemap.xml"].Data); !strings.Contains(sitemap, "<loc>https://example.com/blog/first/</loc>") || !strings.Contains(sitemap, "<lastmod>2016-02-01</lastmod>") {
+		flux.FatalFailed(t, "Unexpected sitemap: %s", sitemap)
+	}
+
+	if rss := string(built["rss.xml"].Data); !strings.Contains(rss, "<link>https://example.com/blog/second/</link>") || strings.Contains(rss, "Home") {
+		flux.FatalFailed(t, "Unexpected feed: %s", rss)
+	}
+
+	flux.LogPassed(t, "Successfully built site of %d files", len(writes))
+}
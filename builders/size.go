@@ -0,0 +1,175 @@
+package builders
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pkgHeader matches the start of each package written by gopherjs in both minified and readable output
+var pkgHeader = regexp.MustCompile(`\$packages\["([^"]+)"\]\s*=\s*\(function\(\)\s*\{`)
+
+// pkgFooter matches the end of each package written by gopherjs in both minified and readable output
+var pkgFooter = regexp.MustCompile(`return \$pkg;\s*\}\)\(\);`)
+
+// PackageSize defines the bytes contributed by a go package to a js output
+type PackageSize struct {
+	Package string `json:"package"`
+	Bytes   int    `json:"bytes"`
+}
+
+// SizeReport provides a breakdown of a js output by the go packages written into it
+type SizeReport struct {
+	Name     string        `json:"name"`
+	Total    int           `json:"total"`
+	Prelude  int           `json:"prelude"` // bytes written outside the packages i.e the gopherjs prelude and the code starting the main package
+	Packages []PackageSize `json:"packages"`
+}
+
+// NewSizeReport builds a SizeReport from the gopherjs output of the given name, the bytes after the end of the last
+// package which start the main package are added to the prelude size, packages are sorted from largest to smallest
+func NewSizeReport(name string, js []byte) *SizeReport {
+	report := &SizeReport{Name: name, Total: len(js)}
+
+	headers := pkgHeader.FindAllSubmatchIndex(js, -1)
+
+	if len(headers) == 0 {
+		report.Prelude = len(js)
+		return report
+	}
+
+	report.Prelude = headers[0][0]
+
+	for index, header := range headers {
+		end := len(js)
+
+		if index+1 < len(headers) {
+			end = headers[index+1][0]
+		} else if footer := pkgFooter.FindIndex(js[header[1]:]); footer != nil {
+			end = header[1] + footer[1]
+			report.Prelude += len(js) - end
+		}
+
+		report.Packages = append(report.Packages, PackageSize{
+			Package: string(js[header[2]:header[3]]),
+			Bytes:   end - header[0],
+		})
+	}
+
+	sort.SliceStable(report.Packages, func(i, j int) bool {
+		return report.Packages[i].Bytes > report.Packages[j].Bytes
+	})
+
+	return report
+}
+
+// Size returns the bytes of the given package in the report or -1 if it was not written into the output
+func (s *SizeReport) Size(pkg string) int {
+	for _, size := range s.Packages {
+		if size.Package == pkg {
+			return size.Bytes
+		}
+	}
+	return -1
+}
+
+// SizeBudget defines the allowed sizes for a js output
+type SizeBudget struct {
+	Total    int            // Optional: maximum bytes of the whole js output, zero disables the check
+	Packages map[string]int // Optional: maximum bytes for each package import path
+	Fail     bool           // Optional: if true, a BudgetError is replied and the outputs other than the size report are not written, else a BudgetWarning is replied
+}
+
+// BudgetViolation describes a size that went above its budget
+type BudgetViolation struct {
+	Name   string `json:"name"`
+	Size   int    `json:"size"`
+	Budget int    `json:"budget"`
+}
+
+// Check returns the list of violations of the budget found in the report
+func (b SizeBudget) Check(report *SizeReport) []BudgetViolation {
+	var violations []BudgetViolation
+
+	if b.Total > 0 && report.Total > b.Total {
+		violations = append(violations, BudgetViolation{Name: report.Name, Size: report.Total, Budget: b.Total})
+	}
+
+	var pkgs []string
+
+	for pkg := range b.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Strings(pkgs)
+
+	for _, pkg := range pkgs {
+		budget := b.Packages[pkg]
+
+		if size := report.Size(pkg); size > budget {
+			violations = append(violations, BudgetViolation{Name: pkg, Size: size, Budget: budget})
+		}
+	}
+
+	return violations
+}
+
+// BudgetWarning is replied by the js builders when the output went over its SizeBudget
+type BudgetWarning struct {
+	Violations []BudgetViolation
+	ID         string // Optional: correlation ID of the change which caused the build
+}
+
+// Correlation returns the correlation ID of the build which went over its budget
+func (b *BudgetWarning) Correlation() string {
+	return b.ID
+}
+
+// BudgetError is replied as an error by the js builders when the output went over its SizeBudget and SizeBudget.Fail is true
+type BudgetError struct {
+	Violations []BudgetViolation
+}
+
+// Error returns the list of violations as a message
+func (b *BudgetError) Error() string {
+	var msgs []string
+
+	for _, v := range b.Violations {
+		msgs = append(msgs, fmt.Sprintf("%s is %d bytes, budget is %d bytes", v.Name, v.Size, v.Budget))
+	}
+
+	return fmt.Sprintf("js size budget exceeded: %s", strings.Join(msgs, ", "))
+}
+
+var sizeReportTemplate = template.Must(template.New("report").Parse(`<!doctype html>
+<html>
+<head><meta charset="utf-8"><title>{{.Name}} size report</title></head>
+<body>
+<h1>{{.Name}}</h1>
+<p>Total: {{.Total}} bytes, Prelude: {{.Prelude}} bytes</p>
+<table>
+<tr><th>Package</th><th>Bytes</th></tr>
+{{range .Packages}}<tr><td>{{.Package}}</td><td>{{.Bytes}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// Render returns the report as html if the file name ends with .html or .htm else as json
+func (s *SizeReport) Render(file string) ([]byte, error) {
+	switch filepath.Ext(file) {
+	case ".html", ".htm":
+		var buf bytes.Buffer
+		if err := sizeReportTemplate.Execute(&buf, s); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return json.MarshalIndent(s, "", "  ")
+}
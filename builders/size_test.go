@@ -0,0 +1,71 @@
+package builders
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+var sizeFixture = []byte(`"use strict";
+(function() {
+var $global;
+$packages["github.com/gopherjs/gopherjs/js"] = (function() {
+	var $pkg = {};
+	return $pkg;
+})();
+$packages["main"]=(function(){var $pkg={};return $pkg;})();
+$go($mainPkg.$init, [], true);
+}).call(this);
+`)
+
+func TestSizeReport(t *testing.T) {
+	report := NewSizeReport("base.js", sizeFixture)
+
+	if report.Total != len(sizeFixture) {
+		flux.FatalFailed(t, "Unexpected total size: %d", report.Total)
+	}
+
+	if len(report.Packages) != 2 {
+		flux.FatalFailed(t, "Expected two packages in report: %+s", report.Packages)
+	}
+
+	if report.Size("main") <= 0 || report.Size("github.com/gopherjs/gopherjs/js") <= 0 {
+		flux.FatalFailed(t, "Expected sizes for each package: %+s", report.Packages)
+	}
+
+	main := []byte(`$packages["main"]=(function(){var $pkg={};return $pkg;})();`)
+	header := bytes.Index(sizeFixture, []byte(`$packages["github.com`))
+	trailer := len(sizeFixture) - (bytes.Index(sizeFixture, main) + len(main))
+
+	if report.Prelude != header+trailer {
+		flux.FatalFailed(t, "Expected the prelude to hold the bytes before the first and after the last package: %d != %d", report.Prelude, header+trailer)
+	}
+
+	if report.Size("main") != len(main) {
+		flux.FatalFailed(t, "Expected the last package to end at its closing call: %d != %d", report.Size("main"), len(main))
+	}
+
+	flux.LogPassed(t, "Successfully built size report: %+s", report.Packages)
+}
+
+func TestSizeBudget(t *testing.T) {
+	report := NewSizeReport("base.js", sizeFixture)
+
+	budget := SizeBudget{
+		Total:    10,
+		Packages: map[string]int{"main": 1000},
+	}
+
+	violations := budget.Check(report)
+
+	if len(violations) != 1 || violations[0].Name != "base.js" {
+		flux.FatalFailed(t, "Expected only the total budget to be exceeded: %+s", violations)
+	}
+
+	if _, err := report.Render("report.html"); err != nil {
+		flux.FatalFailed(t, "Failed to render html report: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully checked budget: %s", (&BudgetError{Violations: violations}).Error())
+}
@@ -0,0 +1,192 @@
+package builders
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/influx6/flux"
+)
+
+// Heading is an entry of the table of contents of a rendered file
+type Heading struct {
+	Level    int        // Level of the heading from 1 to 6
+	ID       string     // ID of the heading element which anchors link to
+	Text     string     // Text of the heading without markup
+	Children []*Heading // Children are the headings of a lower level following this one
+}
+
+// TOCConfig provides the configuration for generating heading ids, anchors and the table of contents
+type TOCConfig struct {
+	Enabled     bool   // Enabled turns on the generation when the config is part of MarkConfig, MarkStreamConfig or SiteConfig
+	Anchors     bool   // Optional: if true an anchor link to the heading is inserted at the start of each heading
+	AnchorText  string // Optional: text of the anchor links, defaults to #
+	Placeholder string // Optional: text replaced by the html table of contents, defaults to [TOC]
+	MinLevel    int    // Optional: lowest heading level listed in the table of contents, defaults to 1
+	MaxLevel    int    // Optional: highest heading level listed in the table of contents, defaults to 6
+}
+
+var (
+	headingTag  = regexp.MustCompile(`(?s)<h([1-6])((?:\s[^>]*)?)>(.*?)</h[1-6]>`)
+	headingID   = regexp.MustCompile(`\sid="([^"]*)"`)
+	htmlTag     = regexp.MustCompile(`<[^>]*>`)
+	tocDefaults = TOCConfig{AnchorText: "#", Placeholder: "[TOC]", MinLevel: 1, MaxLevel: 6}
+)
+
+// defaults returns the config with its unset fields defaulted
+func (c TOCConfig) defaults() TOCConfig {
+	if c.AnchorText == "" {
+		c.AnchorText = tocDefaults.AnchorText
+	}
+
+	if c.Placeholder == "" {
+		c.Placeholder = tocDefaults.Placeholder
+	}
+
+	if c.MinLevel == 0 {
+		c.MinLevel = tocDefaults.MinLevel
+	}
+
+	if c.MaxLevel == 0 {
+		c.MaxLevel = tocDefaults.MaxLevel
+	}
+
+	return c
+}
+
+// Headings gives every heading of the html an id, keeping the ids already set and suffixing repeated ones with a
+// count so they are stable across renders, adds the anchor links if enabled and returns the updated html with the
+// nested headings within the config levels
+func (c TOCConfig) Headings(data []byte) ([]byte, []*Heading) {
+	c = c.defaults()
+
+	var roots []*Heading
+	var stack []*Heading
+	var used = make(map[string]int)
+
+	out := headingTag.ReplaceAllFunc(data, func(tag []byte) []byte {
+		match := headingTag.FindSubmatch(tag)
+		level := int(match[1][0] - '0')
+		attrs, content := string(match[2]), string(match[3])
+		text := strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(content, "")))
+
+		var id string
+
+		if found := headingID.FindStringSubmatch(attrs); found != nil {
+			id = found[1]
+			used[id]++
+		} else {
+			id = uniqueID(slugify(text), used)
+			attrs += fmt.Sprintf(` id="%s"`, id)
+		}
+
+		if c.Anchors {
+			content = fmt.Sprintf(`<a class="anchor" href="#%s" aria-hidden="true">%s</a>`, id, html.EscapeString(c.AnchorText)) + content
+		}
+
+		if level >= c.MinLevel && level <= c.MaxLevel {
+			heading := &Heading{Level: level, ID: id, Text: text}
+
+			for len(stack) > 0 && stack[len(stack)-1].Level >= level {
+				stack = stack[:len(stack)-1]
+			}
+
+			if len(stack) == 0 {
+				roots = append(roots, heading)
+			} else {
+				parent := stack[len(stack)-1]
+				parent.Children = append(parent.Children, heading)
+			}
+
+			stack = append(stack, heading)
+		}
+
+		return []byte(fmt.Sprintf("<h%d%s>%s</h%d>", level, attrs, content, level))
+	})
+
+	return out, roots
+}
+
+// uniqueID returns the slug or the slug suffixed with the number of times it was already used
+func uniqueID(slug string, used map[string]int) string {
+	if slug == "" {
+		slug = "section"
+	}
+
+	id := slug
+
+	for used[id] > 0 {
+		id = fmt.Sprintf("%s-%d", slug, used[slug])
+		used[slug]++
+	}
+
+	used[id]++
+	return id
+}
+
+// TOCHTML returns the headings as a nav of nested lists of links
+func TOCHTML(headings []*Heading) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(`<nav class="toc">`)
+	writeTOC(&buf, headings)
+	buf.WriteString(`</nav>`)
+
+	return buf.Bytes()
+}
+
+func writeTOC(buf *bytes.Buffer, headings []*Heading) {
+	if len(headings) == 0 {
+		return
+	}
+
+	buf.WriteString("<ul>")
+
+	for _, heading := range headings {
+		fmt.Fprintf(buf, `<li><a href="#%s">%s</a>`, heading.ID, html.EscapeString(heading.Text))
+		writeTOC(buf, heading.Children)
+		buf.WriteString("</li>")
+	}
+
+	buf.WriteString("</ul>")
+}
+
+// apply returns the html with heading ids, anchors and the table of contents in place of the placeholder with the
+// headings
+func (c TOCConfig) apply(data []byte) ([]byte, []*Heading) {
+	c = c.defaults()
+	out, headings := c.Headings(data)
+
+	if !bytes.Contains(out, []byte(c.Placeholder)) {
+		return out, headings
+	}
+
+	toc := TOCHTML(headings)
+	placeholder := []byte(c.Placeholder)
+
+	//markdown wraps a placeholder on its own line into a paragraph which the toc replaces
+	out = bytes.Replace(out, append(append([]byte("<p>"), placeholder...), "</p>"...), toc, -1)
+	out = bytes.Replace(out, placeholder, toc, -1)
+
+	return out, headings
+}
+
+// TOC returns a task which gives the headings of the rendered html of the *RenderFile it receives stable ids, adds
+// anchor links if enabled and replaces the placeholder with the table of contents, replying a *RenderFile of the
+// result with the nested headings in its TOC field. It should come after any sanitizer which could strip the ids
+func TOC(config TOCConfig) (flux.Reactor, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		if rf, ok := data.(*RenderFile); ok {
+			out, headings := config.apply(rf.Data)
+			next := rf.withData(out)
+			next.TOC = headings
+			root.Reply(next)
+		}
+	})), nil
+}
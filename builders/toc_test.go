@@ -0,0 +1,51 @@
+package builders
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestTOCHeadings(t *testing.T) {
+	render, _ := MarkdownOptions{}.Renderer()
+	source := []byte("# Guide\n\n[TOC]\n\n## Install & Setup\n\n### Linux\n\n## Usage\n\n### Linux\n\n# Guide\n")
+
+	out, headings := TOCConfig{Anchors: true, MaxLevel: 2}.apply(render(source))
+	html := string(out)
+
+	for _, expected := range []string{
+		`<h1 id="guide"><a class="anchor" href="#guide" aria-hidden="true">#</a>Guide</h1>`,
+		`<h2 id="install-setup">`,
+		`<h3 id="linux">`,
+		`<h3 id="linux-1">`,
+		`<h1 id="guide-1">`,
+		`<nav class="toc"><ul><li><a href="#guide">Guide</a><ul><li><a href="#install-setup">Install &amp; Setup</a></li><li><a href="#usage">Usage</a></li></ul></li><li><a href="#guide-1">Guide</a></li></ul></nav>`,
+	} {
+		if !strings.Contains(html, expected) {
+			flux.FatalFailed(t, "Expected %q in: %s", expected, html)
+		}
+	}
+
+	if strings.Contains(html, "[TOC]") || strings.Contains(html, "<p><nav") {
+		flux.FatalFailed(t, "Expected the placeholder paragraph to be replaced: %s", html)
+	}
+
+	if len(headings) != 2 || len(headings[0].Children) != 2 || headings[0].Children[0].Text != "Install & Setup" || headings[1].ID != "guide-1" {
+		flux.FatalFailed(t, "Unexpected headings: %+v", headings)
+	}
+
+	if again, _ := (TOCConfig{Anchors: true, MaxLevel: 2}).apply(render(source)); string(again) != html {
+		flux.FatalFailed(t, "Expected the ids to be stable across renders")
+	}
+
+	if out, _ := (TOCConfig{}).Headings([]byte(`<h2 id="custom">Title</h2><h2>Custom</h2>`)); string(out) != `<h2 id="custom">Title</h2><h2 id="custom-1">Custom</h2>` {
+		flux.FatalFailed(t, "Expected existing ids to be kept and not reused: %s", out)
+	}
+
+	if err := (TOCConfig{MinLevel: 3, MaxLevel: 2}).Validate(); err == nil {
+		flux.FatalFailed(t, "Expected a MinLevel above the MaxLevel to fail validation")
+	}
+
+	flux.LogPassed(t, "Successfully generated the table of contents")
+}
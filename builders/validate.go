@@ -0,0 +1,440 @@
+package builders
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/alecthomas/chroma/styles"
+)
+
+// FieldError describes a single invalid field of a config
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationError lists all the invalid fields found in a config
+type ValidationError struct {
+	Config string
+	Fields []FieldError
+}
+
+// Error returns all the invalid fields as a single message
+func (v *ValidationError) Error() string {
+	var msgs []string
+
+	for _, field := range v.Fields {
+		msgs = append(msgs, fmt.Sprintf("%s.%s %s", v.Config, field.Field, field.Message))
+	}
+
+	return strings.Join(msgs, "; ")
+}
+
+// Has returns true/false if the given field was found invalid
+func (v *ValidationError) Has(field string) bool {
+	for _, f := range v.Fields {
+		if f.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *ValidationError) add(field, msg string) {
+	v.Fields = append(v.Fields, FieldError{Field: field, Message: msg})
+}
+
+// merge adds the invalid fields of the error of a nested config under the field name, or the error itself as the
+// message of the field if it is not a *ValidationError
+func (v *ValidationError) merge(field string, err error) {
+	if err == nil {
+		return
+	}
+
+	nested, ok := err.(*ValidationError)
+
+	if !ok {
+		v.add(field, err.Error())
+		return
+	}
+
+	for _, f := range nested.Fields {
+		v.add(field+"."+f.Field, f.Message)
+	}
+}
+
+// err returns the ValidationError as an error if it has invalid fields else nil
+func (v *ValidationError) err() error {
+	if len(v.Fields) == 0 {
+		return nil
+	}
+	return v
+}
+
+// ErrNoCommands is returned when a command runner receives an empty list of commands
+var ErrNoCommands = errors.New("commands list cant be empty")
+
+// ErrNilRenderMux is returned when a ByteRenderer is created without a RenderMux
+var ErrNilRenderMux = errors.New("RenderMux cant be nil for ByteRender")
+
+// Validate returns a *ValidationError listing the missing Name and Path fields
+func (b BuildConfig) Validate() error {
+	verr := &ValidationError{Config: "BuildConfig"}
+
+	if b.Name == "" {
+		verr.add("Name", "can not be empty,supply a name for the build")
+	}
+
+	if b.Path == "" {
+		verr.add("Path", "can not be empty,supply a path to store the build")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing the missing Name and Path fields
+func (b BinaryBuildConfig) Validate() error {
+	verr := &ValidationError{Config: "BinaryBuildConfig"}
+
+	if b.Name == "" {
+		verr.add("Name", "can not be empty,supply a name for the build")
+	}
+
+	if b.Path == "" {
+		verr.add("Path", "can not be empty,supply a path to store the build")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing Package and any unknown profile or mode values
+func (j JSBuildConfig) Validate() error {
+	verr := &ValidationError{Config: "JSBuildConfig"}
+
+	if j.Package == "" {
+		verr.add("Package", "can not be empty")
+	}
+
+	validateJSOptions(verr, j.Options())
+
+	if j.Budget.Total < 0 {
+		verr.add("Budget.Total", "can not be negative")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing missing entries, entries without a Name or Package and any unknown
+// profile or mode values
+func (j JSMultiBuildConfig) Validate() error {
+	verr := &ValidationError{Config: "JSMultiBuildConfig"}
+
+	if len(j.Entries) == 0 {
+		verr.add("Entries", "can not be empty")
+	}
+
+	var names = make(map[string]bool)
+
+	for index, entry := range j.Entries {
+		if entry.Name == "" {
+			verr.add(fmt.Sprintf("Entries[%d].Name", index), "can not be empty")
+		} else if names[entry.Name] || entry.Name == j.RuntimeName {
+			verr.add(fmt.Sprintf("Entries[%d].Name", index), fmt.Sprintf("%q is already used", entry.Name))
+		}
+
+		if entry.Package == "" {
+			verr.add(fmt.Sprintf("Entries[%d].Package", index), "can not be empty")
+		}
+
+		names[entry.Name] = true
+	}
+
+	validateJSOptions(verr, j.Options)
+
+	return verr.err()
+}
+
+func validateJSOptions(verr *ValidationError, o JSOptions) {
+	if o.Profile < DefaultProfile || o.Profile > ReleaseProfile {
+		verr.add("Profile", fmt.Sprintf("has unknown value %d", o.Profile))
+	}
+
+	if o.Minify < MinifyDefault || o.Minify > MinifyOff {
+		verr.add("Minify", fmt.Sprintf("has unknown value %d", o.Minify))
+	}
+
+	if o.SourceMap < SourceMapDefault || o.SourceMap > SourceMapNone {
+		verr.add("SourceMap", fmt.Sprintf("has unknown value %d", o.SourceMap))
+	}
+}
+
+// Validate returns a *ValidationError if the Ext is not a plain file extension
+func (m MarkConfig) Validate() error {
+	verr := &ValidationError{Config: "MarkConfig"}
+
+	if strings.ContainsAny(m.Ext, `/\`) {
+		verr.add("Ext", "can not contain path separators")
+	}
+
+	validateMarkdown(verr, m.Markdown)
+	validateHighlight(verr, m.Highlight)
+	validateTOC(verr, m.TOC)
+	validatePolicy(verr, m.Policy)
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing InputDir or an Ext that is not a plain file extension
+func (m MarkStreamConfig) Validate() error {
+	verr := &ValidationError{Config: "MarkStreamConfig"}
+
+	if m.InputDir == "" {
+		verr.add("InputDir", "can not be empty")
+	}
+
+	if strings.ContainsAny(m.Ext, `/\`) {
+		verr.add("Ext", "can not contain path separators")
+	}
+
+	validateMarkdown(verr, m.Markdown)
+	validateHighlight(verr, m.Highlight)
+	validateTOC(verr, m.TOC)
+	validatePolicy(verr, m.Policy)
+
+	if m.Links.Fail && !m.Links.Check {
+		verr.add("Links.Fail", "requires Links.Check")
+	}
+
+	if m.StateFile != "" && !m.Cleanup && !m.Incremental {
+		verr.add("StateFile", "requires Cleanup or Incremental")
+	}
+
+	if m.Incremental && m.Links.Check {
+		verr.add("Links.Check", "can not be combined with Incremental as every page is needed to check links")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing Addr or a Prefix not starting with a /
+func (d DevServerConfig) Validate() error {
+	verr := &ValidationError{Config: "DevServerConfig"}
+
+	if d.Addr == "" {
+		verr.add("Addr", "can not be empty")
+	}
+
+	if d.Prefix != "" && !strings.HasPrefix(d.Prefix, "/") {
+		verr.add("Prefix", "must start with a /")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing Addr or a missing or invalid Target url
+func (p ProxyConfig) Validate() error {
+	verr := &ValidationError{Config: "ProxyConfig"}
+
+	if p.Addr == "" {
+		verr.add("Addr", "can not be empty")
+	}
+
+	if p.Target == "" {
+		verr.add("Target", "can not be empty")
+	} else if target, err := url.Parse(p.Target); err != nil || target.Host == "" {
+		verr.add("Target", "must be a valid url eg. http://localhost:3000")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing the invalid fields of the Build and Proxy configs
+func (b BinaryBuildProxyConfig) Validate() error {
+	verr := &ValidationError{Config: "BinaryBuildProxyConfig"}
+
+	verr.merge("Build", b.Build.Validate())
+	verr.merge("Proxy", b.Proxy.Validate())
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError if the Package is missing
+func (p PackageWatchConfig) Validate() error {
+	verr := &ValidationError{Config: "PackageWatchConfig"}
+
+	if p.Package == "" {
+		verr.add("Package", "can not be empty,supply the import path of the package to watch")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing the missing Path and Ref fields
+func (g GitChangeConfig) Validate() error {
+	verr := &ValidationError{Config: "GitChangeConfig"}
+
+	if g.Path == "" {
+		verr.add("Path", "can not be empty,supply a path within the git repository")
+	}
+
+	if g.Ref == "" {
+		verr.add("Ref", "can not be empty,supply the git ref to compare against")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing TemplateDir
+func (l LayoutConfig) Validate() error {
+	verr := &ValidationError{Config: "LayoutConfig"}
+
+	if l.TemplateDir == "" {
+		verr.add("TemplateDir", "can not be empty")
+	}
+
+	return verr.err()
+}
+
+// Validate returns a *ValidationError listing a missing InputDir or SaveDir, a negative PageSize or an unknown Feed
+func (s SiteConfig) Validate() error {
+	verr := &ValidationError{Config: "SiteConfig"}
+
+	if s.InputDir == "" {
+		verr.add("InputDir", "can not be empty")
+	}
+
+	if s.SaveDir == "" {
+		verr.add("SaveDir", "can not be empty")
+	}
+
+	if s.PageSize < 0 {
+		verr.add("PageSize", "can not be negative")
+	}
+
+	switch s.Feed {
+	case "", "rss", "atom", "none":
+	default:
+		verr.add("Feed", fmt.Sprintf("has unknown value %q, expected rss, atom or none", s.Feed))
+	}
+
+	validateMarkdown(verr, s.Markdown)
+	validateHighlight(verr, s.Highlight)
+	validateTOC(verr, s.TOC)
+	validatePolicy(verr, s.Policy)
+
+	if s.Links.Fail && !s.Links.Check {
+		verr.add("Links.Fail", "requires Links.Check")
+	}
+
+	if s.StateFile != "" && !s.Cleanup && !s.Incremental {
+		verr.add("StateFile", "requires Cleanup or Incremental")
+	}
+
+	if s.Incremental && s.Links.Check {
+		verr.add("Links.Check", "can not be combined with Incremental as every page is needed to check links")
+	}
+
+	return verr.err()
+}
+
+func validateMarkdown(verr *ValidationError, m MarkdownOptions) {
+	if !m.hasEngine() {
+		verr.add("Markdown.Engine", fmt.Sprintf("has unknown value %q, expected one of %s", m.Engine, strings.Join(MarkdownEngines(), ", ")))
+	}
+}
+
+// Validate returns a *ValidationError listing an unknown Style or a CSSFile set with Inline styles
+func (h HighlightConfig) Validate() error {
+	verr := &ValidationError{Config: "HighlightConfig"}
+
+	if _, ok := styles.Registry[h.Style]; h.Style != "" && !ok {
+		verr.add("Style", fmt.Sprintf("has unknown value %q", h.Style))
+	}
+
+	if h.Inline && h.CSSFile != "" {
+		verr.add("CSSFile", "can not be used with Inline styles")
+	}
+
+	return verr.err()
+}
+
+// validateHighlight adds the invalid fields of an enabled highlight config to the ValidationError
+func validateHighlight(verr *ValidationError, h HighlightConfig) {
+	if !h.Enabled {
+		return
+	}
+
+	if err, ok := h.Validate().(*ValidationError); ok {
+		for _, field := range err.Fields {
+			verr.add("Highlight."+field.Field, field.Message)
+		}
+	}
+}
+
+// Validate returns a *ValidationError listing an unknown Base or attribute rules without Attrs or with an invalid
+// Matching expression
+func (p PolicyConfig) Validate() error {
+	verr := &ValidationError{Config: "PolicyConfig"}
+
+	switch p.Base {
+	case "", "ugc", "strict", "none":
+	default:
+		verr.add("Base", fmt.Sprintf("has unknown value %q, expected ugc, strict or none", p.Base))
+	}
+
+	for index, rule := range p.Attributes {
+		if len(rule.Attrs) == 0 {
+			verr.add(fmt.Sprintf("Attributes[%d].Attrs", index), "can not be empty")
+		}
+
+		if _, err := regexp.Compile(rule.Matching); err != nil {
+			verr.add(fmt.Sprintf("Attributes[%d].Matching", index), err.Error())
+		}
+	}
+
+	return verr.err()
+}
+
+// validatePolicy adds the invalid fields of a sanitizing policy config to the ValidationError
+func validatePolicy(verr *ValidationError, p PolicyConfig) {
+	if err, ok := p.Validate().(*ValidationError); ok {
+		for _, field := range err.Fields {
+			verr.add("Policy."+field.Field, field.Message)
+		}
+	}
+}
+
+// Validate returns a *ValidationError listing levels outside 1 to 6 or a MinLevel above the MaxLevel
+func (c TOCConfig) Validate() error {
+	verr := &ValidationError{Config: "TOCConfig"}
+
+	if c.MinLevel < 0 || c.MinLevel > 6 {
+		verr.add("MinLevel", "must be between 1 and 6")
+	}
+
+	if c.MaxLevel < 0 || c.MaxLevel > 6 {
+		verr.add("MaxLevel", "must be between 1 and 6")
+	}
+
+	if c.MinLevel > 0 && c.MaxLevel > 0 && c.MinLevel > c.MaxLevel {
+		verr.add("MinLevel", "can not be above the MaxLevel")
+	}
+
+	return verr.err()
+}
+
+// validateTOC adds the invalid fields of an enabled toc config to the ValidationError
+func validateTOC(verr *ValidationError, c TOCConfig) {
+	if !c.Enabled {
+		return
+	}
+
+	if err, ok := c.Validate().(*ValidationError); ok {
+		for _, field := range err.Fields {
+			verr.add("TOC."+field.Field, field.Message)
+		}
+	}
+}
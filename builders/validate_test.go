@@ -0,0 +1,69 @@
+package builders
+
+import (
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestBuildConfigValidate(t *testing.T) {
+	err := BuildConfig{}.Validate()
+
+	verr, ok := err.(*ValidationError)
+
+	if !ok {
+		flux.FatalFailed(t, "Expected a *ValidationError: %s", err)
+	}
+
+	if !verr.Has("Name") || !verr.Has("Path") {
+		flux.FatalFailed(t, "Expected Name and Path to be listed together: %s", verr)
+	}
+
+	if err := (BuildConfig{Name: "app", Path: "./bin"}).Validate(); err != nil {
+		flux.FatalFailed(t, "Expected valid config: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully validated BuildConfig: %s", verr)
+}
+
+func TestBinaryBuildProxyConfigValidate(t *testing.T) {
+	err := BinaryBuildProxyConfig{Proxy: ProxyConfig{Addr: ":8080"}}.Validate()
+
+	verr, ok := err.(*ValidationError)
+
+	if !ok || !verr.Has("Build.Name") || !verr.Has("Proxy.Target") || verr.Has("Proxy.Addr") {
+		flux.FatalFailed(t, "Expected the nested fields to be listed: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully validated BinaryBuildProxyConfig: %s", verr)
+}
+
+func TestConstructorErrors(t *testing.T) {
+	if _, err := NewGoBuilderWith(BuildConfig{Name: "app"}); err == nil {
+		flux.FatalFailed(t, "Expected NewGoBuilderWith to return an error")
+	}
+
+	if _, err := NewJSBuildLauncher(JSBuildConfig{Profile: BuildProfile(9)}); err == nil {
+		flux.FatalFailed(t, "Expected NewJSBuildLauncher to return an error")
+	} else if verr := err.(*ValidationError); !verr.Has("Package") || !verr.Has("Profile") {
+		flux.FatalFailed(t, "Expected Package and Profile errors: %s", verr)
+	}
+
+	if _, err := NewByteRenderer(nil); err != ErrNilRenderMux {
+		flux.FatalFailed(t, "Expected ErrNilRenderMux: %s", err)
+	}
+
+	if _, err := NewCommandLauncher(nil); err != ErrNoCommands {
+		flux.FatalFailed(t, "Expected ErrNoCommands: %s", err)
+	}
+
+	if _, err := MarkFridayStream(MarkStreamConfig{Ext: "a/b"}); err == nil {
+		flux.FatalFailed(t, "Expected MarkFridayStream to return an error")
+	}
+
+	if err := (MarkStreamConfig{InputDir: ".", Incremental: true, Links: LinkConfig{Check: true}}).Validate(); err == nil {
+		flux.FatalFailed(t, "Expected Incremental to not allow checking links")
+	}
+
+	flux.LogPassed(t, "Successfully returned errors from constructors")
+}
@@ -0,0 +1,7 @@
+package main
+
+import "github.com/gopherjs/gopherjs/js"
+
+func main() {
+	js.Global.Get("console").Call("log", "worker")
+}
@@ -0,0 +1,125 @@
+// Command reactors loads a pipeline file describing reactors tasks and their edges, validates it and runs the
+// resulting graph until interrupted.
+//
+//	reactors -f pipeline.yml
+//	reactors -f pipeline.toml -validate
+//	reactors -tasks
+//	reactors -f pipeline.yml -log json -debug
+//	reactors -f pipeline.yml -metrics :9100
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/builders"
+	"github.com/influx6/reactors/logs"
+	"github.com/influx6/reactors/metrics"
+	"github.com/influx6/reactors/pipeline"
+)
+
+func main() {
+	file := flag.String("f", "pipeline.yml", "path of the pipeline file in yaml, toml or json")
+	validate := flag.Bool("validate", false, "only validate the pipeline file and exit")
+	list := flag.Bool("tasks", false, "list the tasks usable in pipeline files and their config fields")
+	watch := flag.Bool("watch", true, "watch the paths listed in the pipeline and rerun its root tasks on changes")
+	format := flag.String("log", "text", "format of the task logs, text or json")
+	debug := flag.Bool("debug", false, "include debug messages such as file changes in the task logs")
+	metricsAddr := flag.String("metrics", "", "if set, serves prometheus metrics on /metrics and the latest spans on /spans at the address eg. :9100")
+	flag.Parse()
+
+	level := slog.LevelInfo
+
+	if *debug {
+		level = slog.LevelDebug
+	}
+
+	switch *format {
+	case "text":
+		logs.SetLogger(logs.Slog(slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+	case "json":
+		logs.SetLogger(logs.Slog(slog.New(slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level}))))
+	default:
+		fmt.Fprintf(os.Stderr, "reactors: unknown log format %q\n", *format)
+		os.Exit(2)
+	}
+
+	if *list {
+		for _, task := range builders.Tasks() {
+			fmt.Println(task.Help())
+		}
+		return
+	}
+
+	spec, err := pipeline.Load(*file)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reactors: %s\n", err)
+		os.Exit(1)
+	}
+
+	if err := spec.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "reactors: %s\n", err)
+		os.Exit(1)
+	}
+
+	if *validate {
+		fmt.Printf("--> %s is valid: %d tasks, %d edges\n", *file, len(spec.Tasks), len(spec.Edges))
+		return
+	}
+
+	graph, err := pipeline.Build(spec, *watch)
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "reactors: %s\n", err)
+		os.Exit(1)
+	}
+
+	for _, name := range graph.Leaves {
+		task := name
+		graph.Tasks[task].React(func(_ flux.Reactor, err error, _ interface{}) {
+			if err != nil {
+				logs.Error(nil, "task failed", logs.Task(task), logs.Err(err))
+			}
+		}, true)
+	}
+
+	if *metricsAddr != "" {
+		serveMetrics(*metricsAddr)
+	}
+
+	fmt.Printf("--> Running %s\n", *file)
+	graph.Start()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+
+	fmt.Printf("--> Stopping %s\n", *file)
+	graph.Close()
+}
+
+// serveMetrics sets a metrics.Registry as the global recorder and serves it on the address
+func serveMetrics(addr string) {
+	registry := metrics.NewRegistry()
+	metrics.SetRecorder(registry)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", registry)
+	mux.HandleFunc("/spans", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(registry.Spans())
+	})
+
+	go func() {
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			logs.Error(nil, "metrics server failed", logs.F("addr", addr), logs.Err(err))
+		}
+	}()
+}
@@ -0,0 +1,72 @@
+package fs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Envelope carries the correlation ID of the change which caused the data it wraps. Correlation is opt-in: Watch and
+// WatchSet send their change events in an Envelope only with Correlate set, and the fs and builders tasks carry the ID
+// of a correlated signal onto their outputs, either within an Envelope for plain values such as paths and build
+// signals or in the ID field of types such as FileRead and FileWrite. Uncorrelated signals keep their plain values
+type Envelope struct {
+	ID   string
+	Data interface{}
+}
+
+// Correlated is implemented by the data types which carry a correlation ID
+type Correlated interface {
+	Correlation() string
+}
+
+// Correlation returns the correlation ID of the envelope
+func (e *Envelope) Correlation() string {
+	return e.ID
+}
+
+// Correlation returns the correlation ID of the read
+func (f *FileRead) Correlation() string {
+	return f.ID
+}
+
+// Correlation returns the correlation ID of the write
+func (f *FileWrite) Correlation() string {
+	return f.ID
+}
+
+// Correlation returns the correlation ID of the removal
+func (r *RemoveFile) Correlation() string {
+	return r.ID
+}
+
+// NewID returns a new random correlation ID
+func NewID() string {
+	id := make([]byte, 8)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// IDOf returns the correlation ID carried by the data or an empty string if it carries none
+func IDOf(data interface{}) string {
+	if co, ok := data.(Correlated); ok {
+		return co.Correlation()
+	}
+	return ""
+}
+
+// Unwrap returns the data within an Envelope or the data itself if it is not one
+func Unwrap(data interface{}) interface{} {
+	if env, ok := data.(*Envelope); ok {
+		return env.Data
+	}
+	return data
+}
+
+// Wrap returns the data in an Envelope with the correlation ID, the data is returned as is if the ID is empty so
+// uncorrelated signals keep their plain values
+func Wrap(id string, data interface{}) interface{} {
+	if id == "" {
+		return data
+	}
+	return &Envelope{ID: id, Data: data}
+}
@@ -0,0 +1,69 @@
+package fs
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/go-fsnotify/fsnotify"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/metrics"
+)
+
+func TestEnvelope(t *testing.T) {
+	env := Wrap("change-1", "./fs.go")
+
+	if IDOf(env) != "change-1" || Unwrap(env) != "./fs.go" {
+		flux.FatalFailed(t, "Unexpected envelope: %+s", env)
+	}
+
+	if plain := Wrap("", true); plain != true {
+		flux.FatalFailed(t, "Expected uncorrelated data to be left as is: %+s", plain)
+	}
+
+	if IDOf("./fs.go") != "" || IDOf(&FileWrite{ID: "change-2"}) != "change-2" {
+		flux.FatalFailed(t, "Unexpected correlation IDs")
+	}
+
+	flux.LogPassed(t, "Successfully wrapped and unwrapped correlated data")
+}
+
+func TestWatchEventCorrelation(t *testing.T) {
+	ev := fsnotify.Event{Name: "./fs.go", Op: fsnotify.Write}
+
+	if plain, ok := watchEvent(metrics.Discard, "Watch", false, ev).(fsnotify.Event); !ok || plain != ev {
+		flux.FatalFailed(t, "Expected the plain event without Correlate")
+	}
+
+	env := watchEvent(metrics.Discard, "Watch", true, ev)
+
+	if IDOf(env) == "" || Unwrap(env) != ev {
+		flux.FatalFailed(t, "Expected the event within a correlated envelope: %+s", env)
+	}
+
+	flux.LogPassed(t, "Successfully made correlation of change events opt-in")
+}
+
+func TestReaderCorrelation(t *testing.T) {
+	ws := new(sync.WaitGroup)
+	ws.Add(1)
+
+	read := FileReader()
+
+	read.React(func(r flux.Reactor, err error, data interface{}) {
+		defer ws.Done()
+
+		if err != nil {
+			flux.FatalFailed(t, "Unable to read file: %s", err)
+		}
+
+		if fr, ok := data.(*FileRead); !ok || fr.ID != "change-1" {
+			flux.FatalFailed(t, "Expected a FileRead with the correlation ID: %+s", data)
+		}
+
+		flux.LogPassed(t, "Successfully carried correlation ID onto the read")
+	}, true)
+
+	read.Send(&Envelope{ID: "change-1", Data: "./fs.go"})
+	ws.Wait()
+	read.Close()
+}
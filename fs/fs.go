@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"errors"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 
 	"github.com/go-fsnotify/fsnotify"
 	"github.com/influx6/assets"
 	"github.com/influx6/flux"
+	"github.com/influx6/reactors/logs"
+	"github.com/influx6/reactors/metrics"
 )
 
 // WatchConfig provides configuration for the WatchDir and WatchFile tasks
@@ -18,9 +19,13 @@ type WatchConfig struct {
 	Path      string
 	Validator assets.PathValidator
 	Mux       assets.PathMux
+	Correlate bool             // Optional: if true every change event is sent within an *Envelope carrying a new correlation ID
+	Logger    logs.Logger      // Optional: Logger receives the change events and watcher status, defaults to the global logs logger
+	Metrics   metrics.Recorder // Optional: Metrics counts the change events and records their spans, defaults to the global metrics recorder
 }
 
-// Watch returns a task handler that watches a path for changes and passes down the file which changed
+// Watch returns a task handler that watches a path for changes and passes down the fsnotify.Event of the file which
+// changed, within an *Envelope carrying the correlation ID of the change if the config Correlate is set
 func Watch(m WatchConfig) flux.Reactor {
 	var running bool
 	mo := flux.Reactive(func(root flux.Reactor, err error, _ interface{}) {
@@ -42,6 +47,7 @@ func Watch(m WatchConfig) flux.Reactor {
 		}
 
 		running = true
+		logs.Info(m.Logger, "watching", logs.Task("Watch"), logs.Path(m.Path))
 
 		if !stat.IsDir() {
 			flux.GoDefer("Watch", func() {
@@ -64,7 +70,8 @@ func Watch(m WatchConfig) flux.Reactor {
 					select {
 					case ev, ok := <-wo.Events:
 						if ok {
-							root.Reply(ev)
+							logs.Debug(m.Logger, "file changed", logs.Task("Watch"), logs.Path(ev.Name), logs.F("op", ev.Op))
+							root.Reply(watchEvent(m.Metrics, "Watch", m.Correlate, ev))
 						}
 					case erx, ok := <-wo.Errors:
 						if ok {
@@ -120,13 +127,14 @@ func Watch(m WatchConfig) flux.Reactor {
 				case ev, ok := <-wo.Events:
 					if ok {
 						file := filepath.Clean(ev.Name)
+						logs.Debug(m.Logger, "file changed", logs.Task("Watch"), logs.Path(file), logs.F("op", ev.Op))
 						// stat, _ := os.Stat(file)
 						if (&m).Validator != nil {
 							if (&m).Validator(file, nil) {
-								root.Reply(ev)
+								root.Reply(watchEvent(m.Metrics, "Watch", m.Correlate, ev))
 							}
 						} else {
-							root.Reply(ev)
+							root.Reply(watchEvent(m.Metrics, "Watch", m.Correlate, ev))
 						}
 					}
 				case erx, ok := <-wo.Errors:
@@ -155,9 +163,13 @@ type WatchSetConfig struct {
 	Path      []string
 	Validator assets.PathValidator
 	Mux       assets.PathMux
+	Correlate bool             // Optional: if true every change event is sent within an *Envelope carrying a new correlation ID
+	Logger    logs.Logger      // Optional: Logger receives the change events and watcher status, defaults to the global logs logger
+	Metrics   metrics.Recorder // Optional: Metrics counts the change events and records their spans, defaults to the global metrics recorder
 }
 
-// WatchSet unlike Watch is not set for only working with one directory, by providing a WatchSetConfig you can supply multiple directories and files which will be sorted and watch if all paths were found to be invalid then the watcher will be closed and so will the task, an invalid file error will be forwarded down the reactor chain
+// WatchSet unlike Watch is not set for only working with one directory, by providing a WatchSetConfig you can supply multiple directories and files which will be sorted and watch if all paths were found to be invalid then the watcher will be closed and so will the task, an invalid file error will be forwarded down the reactor chain.
+// As with Watch the fsnotify.Event of each change is sent within an *Envelope only if the config Correlate is set
 func WatchSet(m WatchSetConfig) flux.Reactor {
 	var running bool
 	mo := flux.Reactive(func(root flux.Reactor, err error, _ interface{}) {
@@ -203,12 +215,13 @@ func WatchSet(m WatchSetConfig) flux.Reactor {
 		}
 
 		if len(dirlistings) <= 0 && len(files) <= 0 {
-			log.Printf("no dirlistings, will close")
+			logs.Warn(m.Logger, "no valid paths to watch, closing", logs.Task("WatchSet"), logs.F("paths", m.Path))
 			go root.Close()
-			log.Printf("no dirlistings, will close")
 			return
 		}
 
+		logs.Info(m.Logger, "watching", logs.Task("WatchSet"), logs.F("paths", m.Path))
+
 		flux.GoDefer("Watch", func() {
 			defer root.Close()
 
@@ -261,16 +274,17 @@ func WatchSet(m WatchSetConfig) flux.Reactor {
 					break
 				case ev, ok := <-wo.Events:
 					if ok {
+						logs.Debug(m.Logger, "file changed", logs.Task("WatchSet"), logs.Path(ev.Name), logs.F("op", ev.Op))
 						if (&m).Validator != nil {
 							file := filepath.Clean(ev.Name)
 							// log.Printf("checking file: %s", file)
 							if (&m).Validator(file, nil) {
 								// log.Printf("passed file: %s", file)
-								root.Reply(ev)
+								root.Reply(watchEvent(m.Metrics, "WatchSet", m.Correlate, ev))
 							}
 						} else {
 							// log.Printf("backdrop file: %s", ev)
-							root.Reply(ev)
+							root.Reply(watchEvent(m.Metrics, "WatchSet", m.Correlate, ev))
 						}
 					}
 				case erx, ok := <-wo.Errors:
@@ -294,6 +308,20 @@ func WatchSet(m WatchSetConfig) flux.Reactor {
 	return mo
 }
 
+// watchEvent counts the change event and records its span, when correlate is set the event is returned in an
+// Envelope with a new correlation ID whose spans are children of the event span, else the event is returned as is
+func watchEvent(recorder metrics.Recorder, task string, correlate bool, ev fsnotify.Event) interface{} {
+	var id string
+
+	if correlate {
+		id = NewID()
+	}
+
+	metrics.Inc(recorder, metrics.WatchEvents, metrics.L("task", task), metrics.L("op", ev.Op.String()))
+	metrics.Event(recorder, id, "watch.event", metrics.L("task", task), metrics.L("path", ev.Name), metrics.L("op", ev.Op.String()), metrics.L("correlation", id))
+	return Wrap(id, ev)
+}
+
 // ModFileRead provides a task that allows building a FileRead modder,where you mod out the values for a particular FileRead struct
 func ModFileRead(fx func(*FileRead)) flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
@@ -318,11 +346,16 @@ func ModFileWrite(fx func(*FileWrite)) flux.Reactor {
 type FileRead struct {
 	Data []byte
 	Path string
+	ID   string // Optional: correlation ID of the change which caused the read
 }
 
-// FileReader returns a new flux.Reactor that takes a path and reads out returning the file path
+// FileReader returns a new flux.Reactor that takes a path and reads out returning the file path, the correlation ID
+// of an *Envelope wrapped path is set on the FileRead
 func FileReader() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
+		id := IDOf(data)
+		data = Unwrap(data)
+
 		if pr, ok := data.(*FileRead); ok {
 			root.Reply(pr)
 			return
@@ -350,7 +383,7 @@ func FileReader() flux.Reactor {
 					return
 				}
 
-				root.Reply(&FileRead{Data: buf.Bytes(), Path: path})
+				root.Reply(&FileRead{Data: buf.Bytes(), Path: path, ID: id})
 			} else {
 				root.ReplyError(err)
 			}
@@ -367,10 +400,19 @@ var defaultMux = func(s string) string { return s }
 type FileWrite struct {
 	Data []byte
 	Path string
+	ID   string                 // Optional: correlation ID of the change which caused the write
+	Meta map[string]interface{} // Optional: metadata of the written file such as the front matter of markdown
 }
 
-// FileWriter takes the giving data of type FileWriter and writes the value out into a endpoint which is the value of Path in the FileWriter struct, it takes an optional function which reforms the path to save the file
+// FileWriter takes the giving data of type FileWriter and writes the value out into a endpoint which is the value of Path in the FileWriter struct, it takes an optional function which reforms the path to save the file.
+// It records no metrics, use FileWriterWith to record them
 func FileWriter(fx func(string) string) flux.Reactor {
+	return FileWriterWith(fx, metrics.Discard)
+}
+
+// FileWriterWith is a FileWriter which counts the files and bytes it writes in the Recorder, or the global Recorder if
+// nil, along with a write span which is a child of the watch event of the correlation ID of the FileWrite
+func FileWriterWith(fx func(string) string, recorder metrics.Recorder) flux.Reactor {
 	if fx == nil {
 		fx = defaultMux
 	}
@@ -388,9 +430,12 @@ func FileWriter(fx func(string) string) flux.Reactor {
 			//make the directory part incase it does not exists
 			os.MkdirAll(endpointDir, 0700)
 
+			span := metrics.StartSpan(recorder, file.ID, "write", metrics.L("path", endpoint))
+
 			osfile, err := os.Create(endpoint)
 
 			if err != nil {
+				span.End(err)
 				root.ReplyError(err)
 				return
 			}
@@ -398,9 +443,18 @@ func FileWriter(fx func(string) string) flux.Reactor {
 			defer osfile.Close()
 
 			// io.Copy(osfile, file.Data)
-			osfile.Write(file.Data)
+			n, err := osfile.Write(file.Data)
+			span.End(err)
 
-			root.Reply(&FileWrite{Path: endpoint})
+			if err != nil {
+				root.ReplyError(err)
+				return
+			}
+
+			metrics.Inc(recorder, metrics.FilesWritten, metrics.L("task", "FileWriter"))
+			metrics.Add(recorder, metrics.BytesWritten, float64(n), metrics.L("task", "FileWriter"))
+
+			root.Reply(&FileWrite{Path: endpoint, ID: file.ID, Meta: file.Meta})
 		}
 	}))
 }
@@ -456,7 +510,7 @@ func FileAppender(fx func(string) string) flux.Reactor {
 			// io.Copy(osfile, file.Data)
 
 			osfile.Write(file.Data)
-			root.Reply(&FileWrite{Path: endpoint})
+			root.Reply(&FileWrite{Path: endpoint, ID: file.ID, Meta: file.Meta})
 		}
 	}))
 }
@@ -464,9 +518,11 @@ func FileAppender(fx func(string) string) flux.Reactor {
 // RemoveFile represents a file to be removed by a FileRemover task
 type RemoveFile struct {
 	Path string
+	ID   string // Optional: correlation ID of the change which caused the removal
 }
 
-// FileRemover takes a *RemoveFile as the data and removes the path giving by the RemoveFile.Path, to remove all path along using os.Remove, use the FileAllRemover
+// FileRemover takes a *RemoveFile as the data and removes the path giving by the RemoveFile.Path, to remove all path along using os.Remove, use the FileAllRemover.
+// The *RemoveFile is replied once the path is removed
 func FileRemover() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if file, ok := data.(*RemoveFile); ok {
@@ -476,11 +532,14 @@ func FileRemover() flux.Reactor {
 				root.ReplyError(err)
 				return
 			}
+
+			root.Reply(file)
 		}
 	}))
 }
 
-// FileAllRemover takes a *RemoveFile as the data and removes the path using the os.RemoveAll
+// FileAllRemover takes a *RemoveFile as the data and removes the path using the os.RemoveAll, replying the *RemoveFile
+// once the path is removed
 func FileAllRemover() flux.Reactor {
 	return flux.Reactive(flux.SimpleMuxer(func(root flux.Reactor, data interface{}) {
 		if file, ok := data.(*RemoveFile); ok {
@@ -490,6 +549,8 @@ func FileAllRemover() flux.Reactor {
 				root.ReplyError(err)
 				return
 			}
+
+			root.Reply(file)
 		}
 	}))
 }
@@ -501,9 +562,11 @@ type ListingConfig struct {
 	UseRelative bool // optional: if true, will only list in relative paths
 	Validator   assets.PathValidator
 	Mux         assets.PathMux
+	Correlate   bool // optional: if true, the paths are sent within an *Envelope carrying the correlation ID of the signal if it has one
 }
 
 // StreamListings takes a path and generates a assets.DirListing struct when it receives any signal, it will go through all the files within each listings.
+// The paths are sent within an *Envelope when Correlate is set and the signal carries a correlation ID
 func StreamListings(config ListingConfig) (flux.Reactor, error) {
 	dir, err := assets.DirListings(config.Path, config.Validator, config.Mux)
 
@@ -517,14 +580,20 @@ func StreamListings(config ListingConfig) (flux.Reactor, error) {
 			return
 		}
 
+		var id string
+
+		if config.Correlate {
+			id = IDOf(data)
+		}
+
 		// no error occured reloading, so we stream out the directory, list
 		dir.Listings.Wo.RLock()
 		for _, files := range dir.Listings.Tree {
 			if config.DirAlso {
 				if !config.UseRelative {
-					root.Reply(files.AbsDir)
+					root.Reply(Wrap(id, files.AbsDir))
 				} else {
-					root.Reply(filepath.ToSlash(files.Dir))
+					root.Reply(Wrap(id, filepath.ToSlash(files.Dir)))
 				}
 			}
 			files.Tree.Each(func(mod, real string) {
@@ -534,9 +603,9 @@ func StreamListings(config ListingConfig) (flux.Reactor, error) {
 						rel = real
 					}
 					// log.Printf("Sending %s -> %s -> %s", files.AbsDir, real, rel)
-					root.Reply(rel)
+					root.Reply(Wrap(id, rel))
 				} else {
-					root.Reply(filepath.Join(files.Dir, real))
+					root.Reply(Wrap(id, filepath.Join(files.Dir, real)))
 				}
 			})
 		}
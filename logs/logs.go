@@ -0,0 +1,183 @@
+// Package logs provides the pluggable structured logger used by the fs and builders tasks for their status messages,
+// recovered panics and process events. A Logger can be set globally with SetLogger or per task through the Logger
+// field of its config, a nil Logger always falls back to the global one
+package logs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level defines the severity of a log message
+type Level int
+
+const (
+	// DebugLevel is used for frequent events such as file changes
+	DebugLevel Level = iota
+	// InfoLevel is used for status messages such as a process starting
+	InfoLevel
+	// WarnLevel is used for failures which the task recovers from
+	WarnLevel
+	// ErrorLevel is used for failures and recovered panics
+	ErrorLevel
+)
+
+// String returns the name of the level
+func (l Level) String() string {
+	switch l {
+	case DebugLevel:
+		return "DEBUG"
+	case InfoLevel:
+		return "INFO"
+	case WarnLevel:
+		return "WARN"
+	}
+	return "ERROR"
+}
+
+// Field is a key value pair attached to a log message
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// F returns a Field with the given key and value
+func F(key string, value interface{}) Field {
+	return Field{Key: key, Value: value}
+}
+
+// Task returns the task field naming the task logging the message
+func Task(name string) Field {
+	return Field{Key: "task", Value: name}
+}
+
+// Path returns the path field for the file, directory or package concerned by the message
+func Path(path string) Field {
+	return Field{Key: "path", Value: path}
+}
+
+// Pid returns the pid field of a process
+func Pid(pid int) Field {
+	return Field{Key: "pid", Value: pid}
+}
+
+// Duration returns the duration field eg. the time a build took
+func Duration(d time.Duration) Field {
+	return Field{Key: "duration", Value: d}
+}
+
+// Err returns the error field
+func Err(err error) Field {
+	return Field{Key: "error", Value: err}
+}
+
+// Logger defines the interface for receiving the log messages of the tasks
+type Logger interface {
+	Log(level Level, msg string, fields ...Field)
+}
+
+// LoggerFunc adapts a function into a Logger
+type LoggerFunc func(level Level, msg string, fields ...Field)
+
+// Log calls the function
+func (l LoggerFunc) Log(level Level, msg string, fields ...Field) {
+	l(level, msg, fields...)
+}
+
+// Discard is a Logger which drops all messages
+var Discard Logger = LoggerFunc(func(Level, string, ...Field) {})
+
+// Std returns a Logger writing into a *log.Logger as `LEVEL msg key=value ...` lines, messages below the minimum level
+// are dropped
+func Std(l *log.Logger, min Level) Logger {
+	return LoggerFunc(func(level Level, msg string, fields ...Field) {
+		if level < min {
+			return
+		}
+
+		line := []string{level.String(), msg}
+
+		for _, field := range fields {
+			line = append(line, fmt.Sprintf("%s=%v", field.Key, field.Value))
+		}
+
+		l.Print(strings.Join(line, " "))
+	})
+}
+
+// Slog returns a Logger which writes into the *slog.Logger with the fields as attributes
+func Slog(l *slog.Logger) Logger {
+	return LoggerFunc(func(level Level, msg string, fields ...Field) {
+		var attrs []slog.Attr
+
+		for _, field := range fields {
+			attrs = append(attrs, slog.Any(field.Key, field.Value))
+		}
+
+		l.LogAttrs(context.Background(), slogLevel(level), msg, attrs...)
+	})
+}
+
+func slogLevel(level Level) slog.Level {
+	switch level {
+	case DebugLevel:
+		return slog.LevelDebug
+	case InfoLevel:
+		return slog.LevelInfo
+	case WarnLevel:
+		return slog.LevelWarn
+	}
+	return slog.LevelError
+}
+
+var global = struct {
+	rw     sync.RWMutex
+	logger Logger
+}{logger: Std(log.New(log.Writer(), "", log.LstdFlags), InfoLevel)}
+
+// SetLogger sets the global Logger used by tasks without their own Logger, a nil Logger discards all messages
+func SetLogger(l Logger) {
+	if l == nil {
+		l = Discard
+	}
+
+	global.rw.Lock()
+	global.logger = l
+	global.rw.Unlock()
+}
+
+// Get returns the given Logger if not nil else the global Logger
+func Get(l Logger) Logger {
+	if l != nil {
+		return l
+	}
+
+	global.rw.RLock()
+	defer global.rw.RUnlock()
+	return global.logger
+}
+
+// Debug logs the message at DebugLevel into the Logger or the global Logger if nil
+func Debug(l Logger, msg string, fields ...Field) {
+	Get(l).Log(DebugLevel, msg, fields...)
+}
+
+// Info logs the message at InfoLevel into the Logger or the global Logger if nil
+func Info(l Logger, msg string, fields ...Field) {
+	Get(l).Log(InfoLevel, msg, fields...)
+}
+
+// Warn logs the message at WarnLevel into the Logger or the global Logger if nil
+func Warn(l Logger, msg string, fields ...Field) {
+	Get(l).Log(WarnLevel, msg, fields...)
+}
+
+// Error logs the message at ErrorLevel into the Logger or the global Logger if nil
+func Error(l Logger, msg string, fields ...Field) {
+	Get(l).Log(ErrorLevel, msg, fields...)
+}
@@ -0,0 +1,82 @@
+package logs
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+)
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Std(log.New(&buf, "", 0), InfoLevel)
+
+	Debug(logger, "file changed", Path("app.go"))
+	Info(logger, "process started", Task("RunBin"), Pid(42))
+
+	out := buf.String()
+
+	if strings.Contains(out, "file changed") {
+		flux.FatalFailed(t, "Expected debug message to be dropped: %s", out)
+	}
+
+	if out != "INFO process started task=RunBin pid=42\n" {
+		flux.FatalFailed(t, "Unexpected log line: %q", out)
+	}
+
+	flux.LogPassed(t, "Successfully logged into log.Logger: %q", out)
+}
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := Slog(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	Warn(logger, "process stopped", Task("RunGo"), Pid(7), Duration(time.Second))
+
+	var record map[string]interface{}
+
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		flux.FatalFailed(t, "Unable to decode slog record: %s", err)
+	}
+
+	if record["level"] != "WARN" || record["msg"] != "process stopped" || record["task"] != "RunGo" || record["pid"] != float64(7) {
+		flux.FatalFailed(t, "Unexpected slog record: %+s", record)
+	}
+
+	if _, ok := record["duration"]; !ok {
+		flux.FatalFailed(t, "Expected duration field in slog record: %+s", record)
+	}
+
+	flux.LogPassed(t, "Successfully logged into slog.Logger: %+s", record)
+}
+
+func TestGlobalLogger(t *testing.T) {
+	var global, own []string
+
+	SetLogger(LoggerFunc(func(level Level, msg string, fields ...Field) {
+		global = append(global, msg)
+	}))
+	defer SetLogger(nil)
+
+	logger := LoggerFunc(func(level Level, msg string, fields ...Field) {
+		own = append(own, msg)
+	})
+
+	Info(nil, "to global")
+	Error(logger, "to own")
+
+	if len(global) != 1 || global[0] != "to global" {
+		flux.FatalFailed(t, "Expected nil logger to use the global logger: %+s", global)
+	}
+
+	if len(own) != 1 || own[0] != "to own" {
+		flux.FatalFailed(t, "Expected config logger to be used: %+s", own)
+	}
+
+	flux.LogPassed(t, "Successfully routed messages to the global and config loggers")
+}
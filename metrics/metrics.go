@@ -0,0 +1,117 @@
+// Package metrics provides the optional counters, latency histograms and spans recorded by the fs and builders tasks.
+// A Recorder can be set globally with SetRecorder or per task through the Metrics field of its config, a nil Recorder
+// always falls back to the global one which discards everything until set
+package metrics
+
+import (
+	"sync"
+	"time"
+)
+
+// The metric names recorded by the fs and builders tasks
+const (
+	BuildsStarted   = "reactors_builds_started_total"
+	BuildsSucceeded = "reactors_builds_succeeded_total"
+	BuildsFailed    = "reactors_builds_failed_total"
+	BuildDuration   = "reactors_build_duration_seconds"
+	FilesWritten    = "reactors_files_written_total"
+	BytesWritten    = "reactors_written_bytes_total"
+	WatchEvents     = "reactors_watch_events_total"
+	ProcessRestarts = "reactors_process_restarts_total"
+)
+
+// Label is a name value pair identifying a series of a metric
+type Label struct {
+	Name  string
+	Value string
+}
+
+// L returns a Label with the given name and value
+func L(name, value string) Label {
+	return Label{Name: name, Value: value}
+}
+
+// Recorder defines the interface for receiving the metrics and finished spans of the tasks
+type Recorder interface {
+	// Count adds the value to the counter of the name and labels
+	Count(name string, value float64, labels ...Label)
+
+	// Observe adds the value to the histogram of the name and labels
+	Observe(name string, value float64, labels ...Label)
+
+	// Span receives every span once it has ended
+	Span(span *Span)
+}
+
+type discard struct{}
+
+func (discard) Count(string, float64, ...Label)   {}
+func (discard) Observe(string, float64, ...Label) {}
+func (discard) Span(*Span)                        {}
+
+// Discard is a Recorder which drops everything
+var Discard Recorder = discard{}
+
+var global = struct {
+	rw       sync.RWMutex
+	recorder Recorder
+}{recorder: Discard}
+
+// SetRecorder sets the global Recorder used by tasks without their own Recorder, a nil Recorder discards everything
+func SetRecorder(r Recorder) {
+	if r == nil {
+		r = Discard
+	}
+
+	global.rw.Lock()
+	global.recorder = r
+	global.rw.Unlock()
+}
+
+// Get returns the given Recorder if not nil else the global Recorder
+func Get(r Recorder) Recorder {
+	if r != nil {
+		return r
+	}
+
+	global.rw.RLock()
+	defer global.rw.RUnlock()
+	return global.recorder
+}
+
+// Inc adds one to the counter in the Recorder or the global Recorder if nil
+func Inc(r Recorder, name string, labels ...Label) {
+	Get(r).Count(name, 1, labels...)
+}
+
+// Add adds the value to the counter in the Recorder or the global Recorder if nil
+func Add(r Recorder, name string, value float64, labels ...Label) {
+	Get(r).Count(name, value, labels...)
+}
+
+// Since observes the seconds passed since start in the histogram of the Recorder or the global Recorder if nil
+func Since(r Recorder, name string, start time.Time, labels ...Label) {
+	Get(r).Observe(name, time.Since(start).Seconds(), labels...)
+}
+
+// StartBuild counts a started build of the task and starts its build span as a child of the watch event of the
+// correlation ID, the returned function must be called once the build is done to count its success or failure, observe
+// its duration and end the span
+func StartBuild(r Recorder, id, task string, attrs ...Label) func(err error) {
+	start := time.Now()
+	label := L("task", task)
+
+	Inc(r, BuildsStarted, label)
+	span := StartSpan(r, id, "build", append([]Label{label}, attrs...)...)
+
+	return func(err error) {
+		if err != nil {
+			Inc(r, BuildsFailed, label)
+		} else {
+			Inc(r, BuildsSucceeded, label)
+		}
+
+		Since(r, BuildDuration, start, label)
+		span.End(err)
+	}
+}
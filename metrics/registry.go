@@ -0,0 +1,252 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are the histogram upper bounds in seconds used by a Registry created without buckets
+var DefaultBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// series is a counter or histogram value for one set of labels
+type series struct {
+	labels  []Label
+	value   float64
+	buckets []uint64
+	count   uint64
+}
+
+// family holds all the series of a metric name
+type family struct {
+	histogram bool
+	series    map[string]*series
+}
+
+// Registry is an in-memory Recorder which exposes its metrics in the Prometheus text format and keeps the latest
+// finished spans
+type Registry struct {
+	rw       sync.RWMutex
+	buckets  []float64
+	families map[string]*family
+	spans    []*Span
+	maxSpans int
+}
+
+// NewRegistry returns a Registry using the given histogram upper bounds or DefaultBuckets if none
+func NewRegistry(buckets ...float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	sorted := append([]float64{}, buckets...)
+	sort.Float64s(sorted)
+
+	return &Registry{
+		buckets:  sorted,
+		families: make(map[string]*family),
+		maxSpans: 256,
+	}
+}
+
+// get returns the series of the name and labels, creating it if needed
+func (r *Registry) get(name string, histogram bool, labels []Label) *series {
+	fam, ok := r.families[name]
+
+	if !ok {
+		fam = &family{histogram: histogram, series: make(map[string]*series)}
+		r.families[name] = fam
+	}
+
+	sorted := sortLabels(labels)
+	key := formatLabels(sorted)
+	ser, ok := fam.series[key]
+
+	if !ok {
+		ser = &series{labels: sorted}
+
+		if histogram {
+			ser.buckets = make([]uint64, len(r.buckets))
+		}
+
+		fam.series[key] = ser
+	}
+
+	return ser
+}
+
+// Count adds the value to the counter of the name and labels
+func (r *Registry) Count(name string, value float64, labels ...Label) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	r.get(name, false, labels).value += value
+}
+
+// Observe adds the value to the histogram of the name and labels
+func (r *Registry) Observe(name string, value float64, labels ...Label) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	ser := r.get(name, true, labels)
+	ser.value += value
+	ser.count++
+
+	for i, bound := range r.buckets {
+		if value <= bound {
+			ser.buckets[i]++
+		}
+	}
+}
+
+// Span keeps the span, dropping the oldest span once the Registry holds 256
+func (r *Registry) Span(span *Span) {
+	r.rw.Lock()
+	defer r.rw.Unlock()
+
+	r.spans = append(r.spans, span)
+
+	if len(r.spans) > r.maxSpans {
+		r.spans = r.spans[len(r.spans)-r.maxSpans:]
+	}
+}
+
+// Counter returns the value of the counter of the name and labels
+func (r *Registry) Counter(name string, labels ...Label) float64 {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	fam, ok := r.families[name]
+
+	if !ok || fam.histogram {
+		return 0
+	}
+
+	if ser, ok := fam.series[formatLabels(sortLabels(labels))]; ok {
+		return ser.value
+	}
+
+	return 0
+}
+
+// Spans returns the latest finished spans in the order they ended
+func (r *Registry) Spans() []*Span {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	return append([]*Span{}, r.spans...)
+}
+
+// Trace returns the kept spans of the given trace id
+func (r *Registry) Trace(id string) []*Span {
+	var spans []*Span
+
+	for _, span := range r.Spans() {
+		if span.TraceID == id {
+			spans = append(spans, span)
+		}
+	}
+
+	return spans
+}
+
+// WriteTo writes all the metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.rw.RLock()
+	defer r.rw.RUnlock()
+
+	var buf bytes.Buffer
+	var names []string
+
+	for name := range r.families {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		fam := r.families[name]
+
+		var keys []string
+
+		for key := range fam.series {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		if !fam.histogram {
+			fmt.Fprintf(&buf, "# TYPE %s counter\n", name)
+
+			for _, key := range keys {
+				fmt.Fprintf(&buf, "%s%s %s\n", name, key, formatValue(fam.series[key].value))
+			}
+			continue
+		}
+
+		fmt.Fprintf(&buf, "# TYPE %s histogram\n", name)
+
+		for _, key := range keys {
+			ser := fam.series[key]
+
+			for i, bound := range r.buckets {
+				le := append(append([]Label{}, ser.labels...), L("le", formatValue(bound)))
+				fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, formatLabels(le), ser.buckets[i])
+			}
+
+			inf := append(append([]Label{}, ser.labels...), L("le", "+Inf"))
+			fmt.Fprintf(&buf, "%s_bucket%s %d\n", name, formatLabels(inf), ser.count)
+			fmt.Fprintf(&buf, "%s_sum%s %s\n", name, key, formatValue(ser.value))
+			fmt.Fprintf(&buf, "%s_count%s %d\n", name, key, ser.count)
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+// sortLabels returns a copy of the labels sorted by name
+func sortLabels(labels []Label) []Label {
+	sorted := append([]Label{}, labels...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatLabels returns the labels in the Prometheus `{name="value",...}` form or an empty string if none
+func formatLabels(labels []Label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	var parts []string
+
+	for _, label := range labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, label.Name, labelEscaper.Replace(label.Value)))
+	}
+
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// formatValue returns the Prometheus text form of the value
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestRegistryPrometheus(t *testing.T) {
+	registry := NewRegistry(0.5, 1)
+
+	Inc(registry, BuildsStarted, L("task", "Gobuild"))
+	Inc(registry, BuildsStarted, L("task", "Gobuild"))
+	Add(registry, BytesWritten, 512, L("task", "FileWriter"))
+	registry.Observe(BuildDuration, 0.25, L("task", "Gobuild"))
+	registry.Observe(BuildDuration, 2, L("task", "Gobuild"))
+	Inc(registry, WatchEvents, L("path", `say "hi"`))
+
+	if count := registry.Counter(BuildsStarted, L("task", "Gobuild")); count != 2 {
+		flux.FatalFailed(t, "Expected two started builds: %f", count)
+	}
+
+	var buf bytes.Buffer
+
+	if _, err := registry.WriteTo(&buf); err != nil {
+		flux.FatalFailed(t, "Unable to write metrics: %s", err)
+	}
+
+	out := buf.String()
+
+	for _, line := range []string{
+		"# TYPE reactors_builds_started_total counter",
+		`reactors_builds_started_total{task="Gobuild"} 2`,
+		`reactors_written_bytes_total{task="FileWriter"} 512`,
+		"# TYPE reactors_build_duration_seconds histogram",
+		`reactors_build_duration_seconds_bucket{task="Gobuild",le="0.5"} 1`,
+		`reactors_build_duration_seconds_bucket{task="Gobuild",le="1"} 1`,
+		`reactors_build_duration_seconds_bucket{task="Gobuild",le="+Inf"} 2`,
+		`reactors_build_duration_seconds_sum{task="Gobuild"} 2.25`,
+		`reactors_build_duration_seconds_count{task="Gobuild"} 2`,
+		`reactors_watch_events_total{path="say \"hi\""} 1`,
+	} {
+		if !strings.Contains(out, line+"\n") {
+			flux.FatalFailed(t, "Expected line %q in metrics:\n%s", line, out)
+		}
+	}
+
+	flux.LogPassed(t, "Successfully wrote prometheus metrics")
+}
+
+func TestStartBuild(t *testing.T) {
+	registry := NewRegistry()
+
+	StartBuild(registry, "", "JSBuildLauncher")(nil)
+	StartBuild(registry, "", "JSBuildLauncher")(errors.New("compile error"))
+
+	task := L("task", "JSBuildLauncher")
+
+	if registry.Counter(BuildsStarted, task) != 2 || registry.Counter(BuildsSucceeded, task) != 1 || registry.Counter(BuildsFailed, task) != 1 {
+		flux.FatalFailed(t, "Unexpected build counters")
+	}
+
+	spans := registry.Spans()
+
+	if len(spans) != 2 || spans[1].Error != "compile error" {
+		flux.FatalFailed(t, "Expected a span for each build: %+s", spans)
+	}
+
+	flux.LogPassed(t, "Successfully recorded builds")
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Span records a timed operation in the style of OpenTelemetry, spans sharing a TraceID were caused by the same watch
+// event with the ParentID pointing at the span of that event, found by the correlation ID the event and spans share
+type Span struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	Name       string            `json:"name"`
+	StartTime  time.Time         `json:"start_time"`
+	EndTime    time.Time         `json:"end_time"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Error      string            `json:"error,omitempty"`
+
+	recorder Recorder
+	once     sync.Once
+}
+
+// maxEvents is the number of watch event spans kept as parents, the oldest event is dropped once it is exceeded
+const maxEvents = 1024
+
+// events holds the spans of the latest watch events by their correlation ID so the spans of the builds and writes
+// carrying that ID become their children
+var events = struct {
+	rw    sync.RWMutex
+	spans map[string]*Span
+	order []string
+}{spans: make(map[string]*Span)}
+
+// Event records an instant span for the watch event of the correlation ID in the Recorder or the global Recorder if
+// nil. The span starts a new trace and is the parent of the spans started with the same ID, linking the builds and
+// writes of a pipeline to the change which caused them even when changes overlap
+func Event(r Recorder, id, name string, attrs ...Label) *Span {
+	span := newSpan(r, name, nil, attrs)
+
+	if id != "" {
+		events.rw.Lock()
+
+		if _, ok := events.spans[id]; !ok {
+			events.order = append(events.order, id)
+		}
+
+		events.spans[id] = span
+
+		if len(events.order) > maxEvents {
+			delete(events.spans, events.order[0])
+			events.order = events.order[1:]
+		}
+
+		events.rw.Unlock()
+	}
+
+	span.End(nil)
+	return span
+}
+
+// StartSpan starts a span in the Recorder or the global Recorder if nil, the span is a child of the watch event span
+// of the correlation ID if any else it starts its own trace. The span is only recorded once ended
+func StartSpan(r Recorder, id, name string, attrs ...Label) *Span {
+	var parent *Span
+
+	if id != "" {
+		events.rw.RLock()
+		parent = events.spans[id]
+		events.rw.RUnlock()
+	}
+
+	return newSpan(r, name, parent, attrs)
+}
+
+func newSpan(r Recorder, name string, parent *Span, attrs []Label) *Span {
+	span := &Span{
+		SpanID:    newID(8),
+		Name:      name,
+		StartTime: time.Now(),
+		recorder:  Get(r),
+	}
+
+	if parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentID = parent.SpanID
+	} else {
+		span.TraceID = newID(16)
+	}
+
+	if len(attrs) > 0 {
+		span.Attributes = make(map[string]string)
+
+		for _, attr := range attrs {
+			span.Attributes[attr.Name] = attr.Value
+		}
+	}
+
+	return span
+}
+
+// End sets the end time and error of the span and sends it to its Recorder, only the first call has any effect
+func (s *Span) End(err error) {
+	s.once.Do(func() {
+		s.EndTime = time.Now()
+
+		if err != nil {
+			s.Error = err.Error()
+		}
+
+		s.recorder.Span(s)
+	})
+}
+
+// Duration returns the time between the start and end of the span
+func (s *Span) Duration() time.Duration {
+	return s.EndTime.Sub(s.StartTime)
+}
+
+// newID returns a random hex id of the given size in bytes
+func newID(size int) string {
+	id := make([]byte, size)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/influx6/flux"
+)
+
+func TestEventLinksSpans(t *testing.T) {
+	registry := NewRegistry()
+
+	event := Event(registry, "change-1", "watch.event", L("path", "app.go"))
+
+	//a second change overlapping the build of the first must not take over its spans
+	next := Event(registry, "change-2", "watch.event", L("path", "app.js"))
+
+	build := StartSpan(registry, "change-1", "build", L("task", "Gobuild"))
+	build.End(nil)
+
+	write := StartSpan(registry, "change-1", "write", L("path", "app.js"))
+	write.End(nil)
+	write.End(nil)
+
+	trace := registry.Trace(event.TraceID)
+
+	if len(trace) != 3 {
+		flux.FatalFailed(t, "Expected the event, build and write spans in the trace: %+v", trace)
+	}
+
+	for _, span := range trace[1:] {
+		if span.ParentID != event.SpanID {
+			flux.FatalFailed(t, "Expected span %q to be a child of the event", span.Name)
+		}
+	}
+
+	if build.Attributes["task"] != "Gobuild" || build.Duration() < 0 {
+		flux.FatalFailed(t, "Unexpected build span: %+v", build)
+	}
+
+	if next.TraceID == event.TraceID || len(registry.Trace(next.TraceID)) != 1 {
+		flux.FatalFailed(t, "Expected a separate trace for the next event")
+	}
+
+	if orphan := StartSpan(registry, "", "write"); orphan.ParentID != "" || orphan.TraceID == event.TraceID || orphan.TraceID == next.TraceID {
+		flux.FatalFailed(t, "Expected a span without correlation ID to start its own trace: %+v", orphan)
+	}
+
+	flux.LogPassed(t, "Successfully linked spans to their watch event")
+}
@@ -0,0 +1,389 @@
+// Package pipeline loads declarative pipeline files which describe named reactors tasks and the edges between them,
+// validates them and runs the resulting flux.Reactor graph
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/builders"
+	"github.com/influx6/reactors/fs"
+	"gopkg.in/yaml.v2"
+)
+
+// Spec defines a pipeline of named tasks, the edges connecting them and an optional set of paths to watch
+type Spec struct {
+	Watch []string   `json:"watch"` // Optional: paths which trigger the root tasks on changes
+	Tasks []TaskSpec `json:"tasks"`
+	Edges []EdgeSpec `json:"edges"`
+	Dir   string     `json:"-"` // Optional: dir the relative watch and task config paths are resolved against, set by Load
+
+	Correlate bool `json:"correlate"` // Optional: if true the signals of the roots carry a correlation ID within an *fs.Envelope
+}
+
+// TaskSpec defines a single named task in a pipeline, Task is the name of a task in the builders registry eg.
+// GoBuilderWith and Config holds the fields of its config struct eg. BuildConfig
+type TaskSpec struct {
+	Name   string          `json:"name"`
+	Task   string          `json:"task"`
+	Config json.RawMessage `json:"config"`
+}
+
+// EdgeSpec connects the output of the From task into the To task
+type EdgeSpec struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ErrUnknownFormat is returned when a pipeline file is not a .yml, .yaml, .toml or .json file
+var ErrUnknownFormat = errors.New("pipeline format must be one of yaml, toml or json")
+
+// Load reads the pipeline file at the given path, using its extension to select the format. The relative watch paths
+// and the relative paths of the task configs eg. the InputDir of a MarkFridayStream are resolved against the dir of
+// the file instead of the working directory
+func Load(path string) (*Spec, error) {
+	data, err := ioutil.ReadFile(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	spec, err := Parse(data, strings.TrimPrefix(filepath.Ext(path), "."))
+
+	if err != nil {
+		return nil, err
+	}
+
+	spec.Dir = filepath.Dir(path)
+
+	for index, watch := range spec.Watch {
+		spec.Watch[index] = rebase(spec.Dir, watch)
+	}
+
+	return spec, nil
+}
+
+// rebase joins the path to the dir if it is relative
+func rebase(dir, path string) string {
+	if dir == "" || path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(dir, path)
+}
+
+// rebaseFields rebases the relative paths held by the named fields of the config, a pointer to a struct, on the dir
+func rebaseFields(config interface{}, fields []string, dir string) {
+	if config == nil || dir == "" {
+		return
+	}
+
+	for _, name := range fields {
+		field := reflect.ValueOf(config).Elem()
+
+		for _, part := range strings.Split(name, ".") {
+			if field.Kind() != reflect.Struct {
+				field = reflect.Value{}
+				break
+			}
+			field = field.FieldByName(part)
+		}
+
+		if !field.IsValid() || !field.CanSet() {
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.String:
+			field.SetString(rebase(dir, field.String()))
+		case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.String:
+			for i := 0; i < field.Len(); i++ {
+				field.Index(i).SetString(rebase(dir, field.Index(i).String()))
+			}
+		}
+	}
+}
+
+// Parse decodes the pipeline data in the given format i.e yaml, yml, toml or json
+func Parse(data []byte, format string) (*Spec, error) {
+	var raw interface{}
+
+	switch strings.ToLower(format) {
+	case "json":
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+	case "yaml", "yml":
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, err
+		}
+		raw = stringKeys(raw)
+	case "toml":
+		var doc map[string]interface{}
+		if _, err := toml.Decode(string(data), &doc); err != nil {
+			return nil, err
+		}
+		raw = doc
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	//all formats are turned into json which the config structs are decoded from
+	jsdata, err := json.Marshal(raw)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var spec Spec
+
+	if err := json.Unmarshal(jsdata, &spec); err != nil {
+		return nil, err
+	}
+
+	return &spec, nil
+}
+
+// stringKeys turns the map[interface{}]interface{} values produced by yaml into map[string]interface{}
+func stringKeys(v interface{}) interface{} {
+	switch vals := v.(type) {
+	case map[interface{}]interface{}:
+		mapped := make(map[string]interface{})
+		for key, val := range vals {
+			mapped[fmt.Sprintf("%v", key)] = stringKeys(val)
+		}
+		return mapped
+	case []interface{}:
+		for index, val := range vals {
+			vals[index] = stringKeys(val)
+		}
+		return vals
+	}
+	return v
+}
+
+// Validate checks that task names are unique, every task is registered and its config decodes with all required
+// fields set and that edges connect existing tasks without forming a cycle, all problems found are returned together
+func (s *Spec) Validate() error {
+	var problems []string
+	var names = make(map[string]bool)
+
+	for index, task := range s.Tasks {
+		if task.Name == "" {
+			problems = append(problems, fmt.Sprintf("tasks[%d] has no name", index))
+			continue
+		}
+
+		if names[task.Name] {
+			problems = append(problems, fmt.Sprintf("task %q is defined more than once", task.Name))
+		}
+
+		names[task.Name] = true
+
+		if _, _, err := decodeTask(task, s.Dir); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	for _, edge := range s.Edges {
+		if !names[edge.From] {
+			problems = append(problems, fmt.Sprintf("edge from unknown task %q", edge.From))
+		}
+		if !names[edge.To] {
+			problems = append(problems, fmt.Sprintf("edge to unknown task %q", edge.To))
+		}
+	}
+
+	if s.cyclic() {
+		problems = append(problems, "edges form a cycle")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid pipeline: %s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// cyclic returns true if the edges of the spec contain a cycle
+func (s *Spec) cyclic() bool {
+	var next = make(map[string][]string)
+
+	for _, edge := range s.Edges {
+		next[edge.From] = append(next[edge.From], edge.To)
+	}
+
+	const (
+		visiting = 1
+		visited  = 2
+	)
+
+	var state = make(map[string]int)
+	var visit func(string) bool
+
+	visit = func(name string) bool {
+		switch state[name] {
+		case visiting:
+			return true
+		case visited:
+			return false
+		}
+
+		state[name] = visiting
+		for _, to := range next[name] {
+			if visit(to) {
+				return true
+			}
+		}
+		state[name] = visited
+
+		return false
+	}
+
+	for name := range next {
+		if visit(name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Graph represents the running reactors of a pipeline
+type Graph struct {
+	Tasks  map[string]flux.Reactor
+	Roots  []string // names of the tasks without incoming edges
+	Leaves []string // names of the tasks without outgoing edges
+	watch  flux.Reactor
+
+	correlate bool
+}
+
+// Build validates the spec and creates the reactors for its tasks, connecting them along the edges. When watch is
+// true and the spec has watch paths, a fs.WatchSet over them is bound to the root tasks
+func Build(s *Spec, watch bool) (*Graph, error) {
+	if err := s.Validate(); err != nil {
+		return nil, err
+	}
+
+	graph := &Graph{Tasks: make(map[string]flux.Reactor), correlate: s.Correlate}
+
+	for _, task := range s.Tasks {
+		reactor, err := newTask(task, s.Dir)
+
+		if err != nil {
+			graph.Close()
+			return nil, err
+		}
+
+		graph.Tasks[task.Name] = reactor
+	}
+
+	var incoming = make(map[string]bool)
+	var outgoing = make(map[string]bool)
+
+	for _, edge := range s.Edges {
+		graph.Tasks[edge.From].Bind(graph.Tasks[edge.To], true)
+		incoming[edge.To] = true
+		outgoing[edge.From] = true
+	}
+
+	for _, task := range s.Tasks {
+		if !incoming[task.Name] {
+			graph.Roots = append(graph.Roots, task.Name)
+		}
+		if !outgoing[task.Name] {
+			graph.Leaves = append(graph.Leaves, task.Name)
+		}
+	}
+
+	if watch && len(s.Watch) > 0 {
+		graph.watch = fs.WatchSet(fs.WatchSetConfig{Path: s.Watch, Correlate: s.Correlate})
+		for _, name := range graph.Roots {
+			graph.watch.Bind(graph.Tasks[name], false)
+		}
+	}
+
+	return graph, nil
+}
+
+// decodeTask looks up the registered task for the spec and decodes the spec config into a new config of the task,
+// rebasing the relative paths of the config on the dir
+func decodeTask(spec TaskSpec, dir string) (builders.Task, interface{}, error) {
+	task, ok := builders.LookupTask(spec.Task)
+
+	if !ok {
+		return task, nil, fmt.Errorf("task %q uses unknown task %q", spec.Name, spec.Task)
+	}
+
+	config := task.NewConfig()
+
+	if config != nil && len(spec.Config) > 0 && string(spec.Config) != "null" {
+		if err := json.Unmarshal(spec.Config, config); err != nil {
+			return task, nil, fmt.Errorf("task %q has an invalid config: %s", spec.Name, err)
+		}
+	}
+
+	rebaseFields(config, task.Paths, dir)
+
+	if err := task.Validate(config); err != nil {
+		return task, nil, fmt.Errorf("task %q: %s", spec.Name, err)
+	}
+
+	return task, config, nil
+}
+
+// newTask creates the reactor for the task spec with its relative paths rebased on the dir, recovering panics from
+// invalid configs as errors
+func newTask(spec TaskSpec, dir string) (reactor flux.Reactor, err error) {
+	defer func() {
+		if rerr := recover(); rerr != nil {
+			err = fmt.Errorf("task %q: %v", spec.Name, rerr)
+		}
+	}()
+
+	task, config, err := decodeTask(spec, dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reactor, err = task.Factory(config)
+
+	if err != nil {
+		return nil, fmt.Errorf("task %q: %s", spec.Name, err)
+	}
+
+	return reactor, nil
+}
+
+// Start sends a signal into every root task of the graph, with Correlate set the signals share a new correlation ID
+// so the outputs of the run can be grouped together
+func (g *Graph) Start() {
+	var id string
+
+	if g.correlate {
+		id = fs.NewID()
+	}
+
+	for _, name := range g.Roots {
+		g.Tasks[name].Send(fs.Wrap(id, true))
+	}
+}
+
+// Close closes the watcher and every task of the graph
+func (g *Graph) Close() {
+	if g.watch != nil {
+		g.watch.Close()
+	}
+
+	for _, task := range g.Tasks {
+		task.Close()
+	}
+}
@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influx6/flux"
+	"github.com/influx6/reactors/builders"
+)
+
+func TestLoadYAML(t *testing.T) {
+	spec, err := Load("../fixtures/pipeline/pipeline.yml")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to load yaml pipeline: %s", err)
+	}
+
+	if len(spec.Tasks) != 2 || spec.Tasks[0].Task != "GoFridayStream" {
+		flux.FatalFailed(t, "Unexpected tasks: %+s", spec.Tasks)
+	}
+
+	if err := spec.Validate(); err != nil {
+		flux.FatalFailed(t, "Expected valid pipeline: %s", err)
+	}
+
+	_, config, err := decodeTask(spec.Tasks[1], spec.Dir)
+	if err != nil || config.(*builders.JSBuildConfig).Profile != builders.DebugProfile {
+		flux.FatalFailed(t, "Expected debug profile in JSLauncher config: %s %+s", err, config)
+	}
+
+	flux.LogPassed(t, "Successfully loaded yaml pipeline with %d tasks", len(spec.Tasks))
+}
+
+func TestLoadFromOtherDir(t *testing.T) {
+	fixtures, _ := filepath.Abs("../fixtures")
+	wd, _ := os.Getwd()
+
+	if err := os.Chdir(os.TempDir()); err != nil {
+		flux.FatalFailed(t, "Unable to change the working directory: %s", err)
+	}
+
+	defer os.Chdir(wd)
+
+	spec, err := Load(filepath.Join(fixtures, "pipeline", "pipeline.yml"))
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to load yaml pipeline: %s", err)
+	}
+
+	markdown := filepath.Join(fixtures, "markdown")
+
+	if len(spec.Watch) != 1 || spec.Watch[0] != markdown {
+		flux.FatalFailed(t, "Expected the watch path to be resolved against the pipeline file: %+s", spec.Watch)
+	}
+
+	_, config, err := decodeTask(spec.Tasks[0], spec.Dir)
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to decode task: %s", err)
+	}
+
+	if stream := config.(*builders.MarkStreamConfig); stream.InputDir != markdown || stream.SaveDir != filepath.Join(fixtures, "templates") {
+		flux.FatalFailed(t, "Expected the config paths to be resolved against the pipeline file: %+s", stream)
+	}
+
+	_, config, _ = decodeTask(spec.Tasks[1], spec.Dir)
+
+	if js := config.(*builders.JSBuildConfig); js.Folder != filepath.Join(fixtures, "js") || js.Package != "github.com/influx6/reactors/builders/base" {
+		flux.FatalFailed(t, "Expected only the path fields to be resolved: %+s", js)
+	}
+
+	flux.LogPassed(t, "Successfully resolved the pipeline paths from %s", os.TempDir())
+}
+
+func TestLoadTOML(t *testing.T) {
+	spec, err := Load("../fixtures/pipeline/pipeline.toml")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to load toml pipeline: %s", err)
+	}
+
+	if len(spec.Edges) != 1 || spec.Edges[0].From != "build" {
+		flux.FatalFailed(t, "Unexpected edges: %+s", spec.Edges)
+	}
+
+	if err := spec.Validate(); err != nil {
+		flux.FatalFailed(t, "Expected valid pipeline: %s", err)
+	}
+
+	flux.LogPassed(t, "Successfully loaded toml pipeline with %d tasks", len(spec.Tasks))
+}
+
+func TestDecodeDuration(t *testing.T) {
+	spec, err := Parse([]byte(`
+tasks:
+  - name: app
+    task: BinaryBuildProxy
+    config:
+      build:
+        name: app
+        path: ./bin
+      proxy:
+        addr: ":8080"
+        target: http://localhost:3000
+        timeout: 1m30s
+`), "yaml")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse yaml pipeline: %s", err)
+	}
+
+	_, config, err := decodeTask(spec.Tasks[0], spec.Dir)
+
+	if err != nil || time.Duration(config.(*builders.BinaryBuildProxyConfig).Proxy.Timeout) != 90*time.Second {
+		flux.FatalFailed(t, "Expected the proxy timeout to decode from a duration string: %s %+v", err, config)
+	}
+
+	flux.LogPassed(t, "Successfully decoded a duration through the pipeline")
+}
+
+func TestValidate(t *testing.T) {
+	spec, err := Parse([]byte(`{
+		"tasks": [
+			{"name": "a", "task": "FileReader"},
+			{"name": "b", "task": "Unknown"},
+			{"name": "a", "task": "FileWriter"},
+			{"name": "d", "task": "GoBuilderWith", "config": {"name": "app"}}
+		],
+		"edges": [
+			{"from": "a", "to": "c"},
+			{"from": "a", "to": "b"},
+			{"from": "b", "to": "a"}
+		]
+	}`), "json")
+
+	if err != nil {
+		flux.FatalFailed(t, "Failed to parse json pipeline: %s", err)
+	}
+
+	err = spec.Validate()
+
+	if err == nil {
+		flux.FatalFailed(t, "Expected invalid pipeline")
+	}
+
+	for _, problem := range []string{"more than once", "unknown task \"Unknown\"", "unknown task \"c\"", "cycle", "requires the config fields: Path"} {
+		if !strings.Contains(err.Error(), problem) {
+			flux.FatalFailed(t, "Expected %q in validation error: %s", problem, err)
+		}
+	}
+
+	flux.LogPassed(t, "Successfully reported all problems: %s", err)
+}